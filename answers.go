@@ -0,0 +1,251 @@
+package pashua
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingAnswer is matched by the error returned in non-interactive
+// mode if a mandatory element has neither an answer nor a default
+var ErrMissingAnswer = errors.New("missing answer")
+
+// AnswerFile holds the answers for non-interactive runs, keyed by the
+// DialogID or the title of a window and then by element key
+type AnswerFile map[string]map[string]string
+
+// LoadAnswerFile reads an answer file in JSON or YAML format. The format
+// is taken from the extension (.json, .yaml or .yml) or, without one of
+// those, from the content. For YAML only the two-level mapping of an
+// answer file is supported:
+//
+//	"Dialog title":
+//	  name: Wallace
+//	  ok: 1
+//
+// In JSON, numbers and booleans are accepted as values as well
+func LoadAnswerFile(filename string) (AnswerFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	isJSON := ext == ".json" || ext != ".yaml" && ext != ".yml" && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+	var answers AnswerFile
+	if isJSON {
+		answers, err = parseAnswersJSON(data)
+	} else {
+		answers, err = parseAnswersYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("answer file %s: %w", filename, err)
+	}
+	return answers, nil
+}
+
+// parseAnswersJSON reads a JSON answer file, converting scalar values to strings
+func parseAnswersJSON(data []byte) (AnswerFile, error) {
+	raw := make(map[string]map[string]interface{})
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	answers := make(AnswerFile)
+	for dialog, values := range raw {
+		answers[dialog] = make(map[string]string)
+		for key, value := range values {
+			switch v := value.(type) {
+			case string:
+				answers[dialog][key] = v
+			case bool:
+				answers[dialog][key] = formatBool(v)
+			case float64:
+				answers[dialog][key] = strconv.FormatFloat(v, 'f', -1, 64)
+			case nil:
+				answers[dialog][key] = ""
+			default:
+				return nil, fmt.Errorf("dialog %q: value for %q is not a scalar", dialog, key)
+			}
+		}
+	}
+	return answers, nil
+}
+
+// parseAnswersYAML reads the YAML subset described at LoadAnswerFile
+func parseAnswersYAML(data []byte) (AnswerFile, error) {
+	answers := make(AnswerFile)
+	dialog := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		key, value, found := cutYAML(trimmed)
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", number)
+		}
+		if line == trimmed {
+			if value != "" {
+				return nil, fmt.Errorf("line %d: expected a dialog with answers", number)
+			}
+			dialog = key
+			answers[dialog] = make(map[string]string)
+			continue
+		}
+		if dialog == "" {
+			return nil, fmt.Errorf("line %d: answer outside of a dialog", number)
+		}
+		answers[dialog][key] = value
+	}
+	return answers, scanner.Err()
+}
+
+// cutYAML splits a "key: value" line and removes quotes around both parts
+func cutYAML(line string) (string, string, bool) {
+	key, value := "", ""
+	if line[0] == '"' || line[0] == '\'' {
+		end := strings.IndexByte(line[1:], line[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, line = line[1:end+1], line[end+2:]
+		if !strings.HasPrefix(line, ":") {
+			return "", "", false
+		}
+		value = line[1:]
+	} else {
+		pos := strings.Index(line, ":")
+		if pos <= 0 {
+			return "", "", false
+		}
+		key, value = strings.TrimSpace(line[:pos]), line[pos+1:]
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return key, unquoted, true
+			}
+		}
+		value = value[1 : len(value)-1]
+	} else if pos := strings.Index(value, " #"); pos >= 0 {
+		value = strings.TrimSpace(value[:pos])
+	}
+	return key, value, true
+}
+
+// Answer returns the values for a window without showing it: the defaults
+// of all elements, overridden by the answers stored for the DialogID of the
+// window or, if there is none, for its title. If a button is answered with
+// "1", all other buttons are set to "0". Answers for unknown elements and
+// mandatory elements that end up empty result in an error
+func (answers AnswerFile) Answer(win *PashuaWindow) (map[string]string, error) {
+	return answers.answer(answerDocument(win), win.DialogID)
+}
+
+// AnswerDocument works like Answer for a parsed configuration, which has
+// no DialogID, so the answers are looked up by the title of the window
+func (answers AnswerFile) AnswerDocument(doc *Document) (map[string]string, error) {
+	return answers.answer(doc, "")
+}
+
+// answer looks up the answers for doc by dialogID or its title
+func (answers AnswerFile) answer(doc *Document, dialogID string) (map[string]string, error) {
+	values := doc.DefaultAnswers()
+	stored, found := answers[dialogID]
+	if !found || dialogID == "" {
+		stored = answers[doc.title()]
+	}
+	buttons, mandatory := []string{}, []string{}
+	for _, elem := range doc.Elements {
+		switch elem.Type {
+		case "button", "defaultbutton", "cancelbutton":
+			buttons = append(buttons, elem.Key)
+		}
+		if value, _ := elem.Get("mandatory"); value == "1" {
+			mandatory = append(mandatory, elem.Key)
+		}
+	}
+	for key, value := range stored {
+		if _, found := values[key]; !found {
+			return nil, fmt.Errorf("answer for unknown element %q", key)
+		}
+		if value == "1" && hasOption(buttons, key) {
+			for _, button := range buttons {
+				values[button] = "0"
+			}
+		}
+		values[key] = value
+	}
+	for _, key := range mandatory {
+		if values[key] == "" {
+			return nil, fmt.Errorf("%w for mandatory element %q", ErrMissingAnswer, key)
+		}
+	}
+	return values, nil
+}
+
+// answerDocument converts the window into a Document,
+// skipping components that cannot be encoded
+func answerDocument(win *PashuaWindow) *Document {
+	doc := &Document{Window: windowAttributes(win)}
+	win.documentElements(true, nil, func(elem DocumentElement) error {
+		doc.Elements = append(doc.Elements, elem)
+		return nil
+	})
+	return doc
+}
+
+// title returns the title of the window
+func (doc *Document) title() string {
+	for _, attr := range doc.Window {
+		if attr.Name == "title" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// DefaultAnswers returns the values Pashua would return for the window
+// if the user accepted every default and clicked the default button.
+// Popups without a default return their first option, unchecked
+// checkboxes "0", text and image elements return nothing
+func DefaultAnswers(win *PashuaWindow) map[string]string {
+	return answerDocument(win).DefaultAnswers()
+}
+
+// DefaultAnswers works like the function DefaultAnswers for a document,
+// e.g. one read by ParseDocument
+func (doc *Document) DefaultAnswers() map[string]string {
+	result := make(map[string]string)
+	for _, elem := range doc.Elements {
+		switch elem.Type {
+		case "text", "image":
+			continue
+		case "button", "cancelbutton":
+			result[elem.Key] = "0"
+			continue
+		case "defaultbutton":
+			result[elem.Key] = "1"
+			continue
+		}
+		value, _ := elem.Get("default")
+		if value == "" && elem.Type == "checkbox" {
+			value = "0"
+		}
+		if options := elem.Values("option"); value == "" && elem.Type == "popup" && len(options) > 0 {
+			value = options[0]
+		}
+		result[elem.Key] = value
+	}
+	return result
+}
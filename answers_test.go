@@ -0,0 +1,176 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeAnswerFile writes content to a file with the given name in a
+// temporary directory and returns its path
+func writeAnswerFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadAnswerFileYAML(t *testing.T) {
+	content := `---
+# answers for the CI run
+"Dialog Box":
+  tf: Wallace
+  'quoted key': "line 1\nline 2"
+  single: 'kept # as is'
+  comment: value # dropped
+  empty:
+  ok: 1
+settings:
+  name: Gromit
+`
+	for _, name := range []string{"answers.yaml", "answers.yml", "answers"} {
+		answers, err := LoadAnswerFile(writeAnswerFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := AnswerFile{
+			"Dialog Box": {
+				"tf": "Wallace", "quoted key": "line 1\nline 2", "single": "kept # as is",
+				"comment": "value", "empty": "", "ok": "1",
+			},
+			"settings": {"name": "Gromit"},
+		}
+		if !reflect.DeepEqual(answers, want) {
+			t.Errorf("%s: answers = %q, want %q", name, answers, want)
+		}
+	}
+}
+
+func TestLoadAnswerFileJSON(t *testing.T) {
+	content := `{"Dialog Box": {"tf": "Wallace", "ok": 1, "cb": true, "ratio": 0.5, "none": null}}`
+	for _, name := range []string{"answers.json", "answers"} {
+		answers, err := LoadAnswerFile(writeAnswerFile(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := AnswerFile{"Dialog Box": {"tf": "Wallace", "ok": "1", "cb": "1", "ratio": "0.5", "none": ""}}
+		if !reflect.DeepEqual(answers, want) {
+			t.Errorf("%s: answers = %q, want %q", name, answers, want)
+		}
+	}
+}
+
+func TestLoadAnswerFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		msg     string
+	}{
+		{"a.yaml", "dialog:\n  no colon here\n", "line 2"},
+		{"a.yaml", "  tf: Wallace\n", "outside of a dialog"},
+		{"a.yaml", "dialog: value\n", "expected a dialog"},
+		{"a.yaml", "'unterminated: x\n", "line 1"},
+		{"a.json", `{"d": {"tf": ["a", "b"]}}`, "not a scalar"},
+		{"a.json", `{"d": `, "answer file"},
+	}
+	for _, test := range tests {
+		_, err := LoadAnswerFile(writeAnswerFile(t, test.name, test.content))
+		if err == nil || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%q: error %v, want one containing %q", test.content, err, test.msg)
+		}
+	}
+}
+
+// answerWindow is a dialog with a mandatory field, a checkbox and two buttons
+func answerWindow() *PashuaWindow {
+	win := &PashuaWindow{Title: "Dialog Box"}
+	win.Elements.Add("tf", PashuaTextField{Label: "Name", Mandatory: true})
+	win.Elements.Add("pop", PashuaPopup{Label: "Size", Option: []string{"small", "large"}})
+	win.Elements.Add("txt", PashuaText{Text: "Some text"})
+	win.Elements.Add("cb", PashuaCheckbox{Label: "Debug"})
+	win.Elements.Add("cancel", PashuaCancelButton{})
+	win.Elements.Add("ok", PashuaDefaultButton{})
+	return win
+}
+
+func TestAnswer(t *testing.T) {
+	answers := AnswerFile{
+		"Dialog Box": {"tf": "Wallace"},
+		"by-id":      {"tf": "Gromit", "cancel": "1"},
+	}
+	got, err := answers.Answer(answerWindow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"tf": "Wallace", "pop": "small", "cb": "0", "cancel": "0", "ok": "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("by title: %q, want %q", got, want)
+	}
+
+	win := answerWindow()
+	win.DialogID = "by-id"
+	got, err = answers.Answer(win)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{"tf": "Gromit", "pop": "small", "cb": "0", "cancel": "1", "ok": "0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("by DialogID: %q, want %q", got, want)
+	}
+}
+
+func TestAnswerErrors(t *testing.T) {
+	if _, err := (AnswerFile{}).Answer(answerWindow()); !errors.Is(err, ErrMissingAnswer) {
+		t.Errorf("empty mandatory field: error %v, want ErrMissingAnswer", err)
+	}
+	answers := AnswerFile{"Dialog Box": {"tf": "Wallace", "bogus": "1"}}
+	if _, err := answers.Answer(answerWindow()); err == nil || !strings.Contains(err.Error(), `"bogus"`) {
+		t.Errorf("unknown element: error %v", err)
+	}
+}
+
+func TestRunConfigAnswersCustomElements(t *testing.T) {
+	filename := writeAnswerFile(t, "answers.yaml", "Levels:\n  level: 7\n")
+	client := NewClient(WithAnswerFile(filename))
+	config := "*.title=Levels\nlevel.type=slider\nlevel.max=10\nok.type=defaultbutton\n"
+	got, err := client.RunConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"level": "7", "ok": "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("answers = %q, want %q", got, want)
+	}
+}
+
+func TestRunnerWinsOverAnswerFile(t *testing.T) {
+	t.Setenv("PASHUA_ANSWERS", writeAnswerFile(t, "answers.yaml", "Dialog Box:\n  tf: Wallace\n"))
+	runner := &FakeRunner{Answers: []map[string]string{{"tf": "Gromit", "ok": "1"}}}
+	res, err := NewClient(WithRunner(runner)).Run(context.Background(), answerWindow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := res.String("tf"); name != "Gromit" || len(runner.Configs) != 1 {
+		t.Errorf("tf = %q after %d runs, want the runner's answer", name, len(runner.Configs))
+	}
+	if _, err := NewClient(WithRunner(runner)).RunConfig(context.Background(), "*.title=Dialog Box\n"); !errors.Is(err, ErrNoAnswer) {
+		t.Errorf("RunConfig: error %v, want the runner's ErrNoAnswer", err)
+	}
+}
+
+func TestAnswerModeSkipsImageCheck(t *testing.T) {
+	win := answerWindow()
+	win.Elements.Add("logo", PashuaImage{Path: "/Applications/Missing.app/Contents/Resources/logo.png"})
+	client := NewClient(WithAnswerFile(writeAnswerFile(t, "answers.yaml", "Dialog Box:\n  tf: Wallace\n")))
+	if _, err := client.Run(context.Background(), win); err != nil {
+		t.Errorf("answer mode: %v", err)
+	}
+	if err := win.Validate(); err == nil {
+		t.Error("Validate accepted the missing image")
+	}
+}
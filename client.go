@@ -0,0 +1,316 @@
+package pashua
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client runs Pashua dialogs. It is constructed once with options,
+// locates the Pashua binary on first use and remembers its location.
+// The zero value is not usable, create a Client with NewClient
+type Client struct {
+	path       string
+	searchDirs []string
+	locator    *Locator
+	runner     Runner
+	timeout    time.Duration
+	logger     *log.Logger
+	env        []string
+
+	version       *Version
+	strictVersion bool
+	answerFile    string
+
+	mu        sync.Mutex
+	located   string
+	detected  *Version
+	detectErr error
+}
+
+// ClientOption configures a Client created by NewClient
+type ClientOption func(*Client)
+
+// WithPath sets the path of the Pashua binary, it is checked
+// before the search directories and the standard locations
+func WithPath(path string) ClientOption {
+	return func(c *Client) { c.path = path }
+}
+
+// WithSearchDirs adds directories that are searched for Pashua
+// (or Pashua.app) before the standard locations
+func WithSearchDirs(dirs ...string) ClientOption {
+	return func(c *Client) { c.searchDirs = append(c.searchDirs, dirs...) }
+}
+
+// WithLocator sets the Locator used to search Pashua,
+// the search directories of the client are added to it
+func WithLocator(locator *Locator) ClientOption {
+	return func(c *Client) { c.locator = locator }
+}
+
+// WithRunner sets the Runner used instead of executing Pashua
+func WithRunner(runner Runner) ClientOption {
+	return func(c *Client) { c.runner = runner }
+}
+
+// WithTimeout limits how long a dialog may stay open,
+// Pashua is killed once the timeout has passed
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = timeout }
+}
+
+// WithLogger sets a logger for the location of Pashua and failed runs
+func WithLogger(logger *log.Logger) ClientOption {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithEnv adds "KEY=value" environment variables for the Pashua process
+func WithEnv(env ...string) ClientOption {
+	return func(c *Client) { c.env = append(c.env, env...) }
+}
+
+// WithVersion sets the Pashua version to encode windows for,
+// instead of reading it from the Info.plist of Pashua.app
+func WithVersion(v Version) ClientOption {
+	return func(c *Client) { c.version = &v }
+}
+
+// WithStrictVersion makes Run fail with an *UnsupportedAttributeError
+// for attributes the Pashua version does not support, instead of
+// leaving them out
+func WithStrictVersion() ClientOption {
+	return func(c *Client) { c.strictVersion = true }
+}
+
+// WithAnswerFile enables non-interactive mode: instead of showing
+// the dialogs, the answers are taken from the given JSON or YAML file
+// (see LoadAnswerFile and AnswerFile.Answer). Without this option the
+// mode is enabled by setting $PASHUA_ANSWERS to the name of the file.
+// The mode is ignored when a Runner was set with WithRunner
+func WithAnswerFile(filename string) ClientOption {
+	return func(c *Client) { c.answerFile = filename }
+}
+
+// NewClient creates a Client with the given options
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// defaultClient is used by the package functions without explicit path
+var defaultClient = NewClient()
+
+// clientFor returns the client used by the package functions: one using
+// DefaultRunner if it is set, the shared default client for an empty
+// path and a new client for an explicit path
+func clientFor(pashuaPath string) *Client {
+	if DefaultRunner != nil {
+		return NewClient(WithRunner(DefaultRunner))
+	}
+	if pashuaPath == "" {
+		return defaultClient
+	}
+	return NewClient(WithPath(pashuaPath))
+}
+
+// Locate returns the path of the Pashua binary. The result
+// of the first successful search is cached
+func (c *Client) Locate() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.located != "" {
+		return c.located, nil
+	}
+	locator := c.getLocator()
+	located, err := locator.Locate(c.path)
+	if err != nil {
+		return "", err
+	}
+	c.logf("located pashua at %s", located)
+	c.located = located
+	return located, nil
+}
+
+// Version returns the version of Pashua, either the one set with
+// WithVersion or the one read from the Info.plist next to the located
+// binary. The detected version, or the error detecting it, is cached
+func (c *Client) Version() (Version, error) {
+	if c.version != nil {
+		return *c.version, nil
+	}
+	located, err := c.Locate()
+	if err != nil {
+		return Version{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.detected != nil {
+		return *c.detected, nil
+	}
+	if c.detectErr != nil {
+		return Version{}, c.detectErr
+	}
+	locator := c.getLocator()
+	v, err := locator.Version(located)
+	if err != nil {
+		c.detectErr = err
+		return Version{}, err
+	}
+	c.logf("detected pashua version %s", v)
+	c.detected = &v
+	return v, nil
+}
+
+// RunConfig runs Pashua with a configuration string and returns the parsed output
+func (c *Client) RunConfig(ctx context.Context, config string) (map[string]string, error) {
+	if answers, err := c.answers(); answers != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		doc, err := ParseDocument(strings.NewReader(config))
+		if err != nil {
+			return nil, err
+		}
+		return answers.AnswerDocument(doc)
+	}
+	return c.run(ctx, configString(config))
+}
+
+// run runs Pashua for the configuration and parses its output. A
+// StreamRunner gets the configuration as it is written, other runners
+// as string
+func (c *Client) run(ctx context.Context, config io.WriterTo) (map[string]string, error) {
+	result := make(map[string]string)
+	runner, err := c.getRunner()
+	if err != nil {
+		return nil, err
+	}
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	var output string
+	if stream, ok := runner.(StreamRunner); ok {
+		output, err = stream.RunFrom(ctx, config)
+	} else {
+		text, ok := config.(configString)
+		if !ok {
+			var sb strings.Builder
+			if _, err := config.WriteTo(&sb); err != nil {
+				return nil, err
+			}
+			text = configString(sb.String())
+		}
+		output, err = runner.Run(ctx, string(text))
+	}
+	if err != nil {
+		c.logf("running pashua failed: %v", err)
+		return result, err
+	}
+	result = ParsePashuaOutput(output)
+	return result, nil
+}
+
+// Run validates the window (unless NoValidate is set), encodes it,
+// runs Pashua and returns the output as Result. In non-interactive mode
+// image paths are not checked
+func (c *Client) Run(ctx context.Context, win *PashuaWindow) (*Result, error) {
+	answers, err := c.answers()
+	if err != nil {
+		return nil, err
+	}
+	if !win.NoValidate {
+		// the images of a dialog written for a Mac need not exist
+		// on the machine answering it from a file
+		if err := win.validate(answers == nil); err != nil {
+			return nil, err
+		}
+	}
+	if answers != nil {
+		if _, err := win.Encode(); err != nil {
+			return nil, err
+		}
+		values, err := answers.Answer(win)
+		if err != nil {
+			return nil, err
+		}
+		return NewResult(win, values), nil
+	}
+	values, err := c.run(ctx, windowConfig{win: win, target: c.versionTarget()})
+	if err != nil {
+		return nil, err
+	}
+	return NewResult(win, values), nil
+}
+
+// answers loads the answer file in non-interactive mode,
+// it returns nil if the mode is not enabled. An explicit Runner
+// (including DefaultRunner and pashuatest) takes precedence over
+// the answer file and $PASHUA_ANSWERS
+func (c *Client) answers() (AnswerFile, error) {
+	if c.runner != nil {
+		return nil, nil
+	}
+	filename := c.answerFile
+	if filename == "" {
+		filename = os.Getenv("PASHUA_ANSWERS")
+	}
+	if filename == "" {
+		return nil, nil
+	}
+	c.logf("answering dialogs from %s", filename)
+	return LoadAnswerFile(filename)
+}
+
+// versionTarget returns the Pashua version windows are encoded for
+// or nil if it is unknown, e.g. because a custom Runner is used
+func (c *Client) versionTarget() *versionTarget {
+	if c.version == nil && c.runner != nil {
+		return nil
+	}
+	v, err := c.Version()
+	if err != nil {
+		return nil
+	}
+	return &versionTarget{version: v, strict: c.strictVersion}
+}
+
+// getLocator returns the Locator of the client
+// with the search directories of the client added
+func (c *Client) getLocator() Locator {
+	locator := Locator{}
+	if c.locator != nil {
+		locator = *c.locator
+	}
+	locator.SearchDirs = append(append([]string{}, locator.SearchDirs...), c.searchDirs...)
+	return locator
+}
+
+// getRunner returns the configured Runner or an ExecRunner
+// for the located Pashua binary
+func (c *Client) getRunner() (Runner, error) {
+	if c.runner != nil {
+		return c.runner, nil
+	}
+	located, err := c.Locate()
+	if err != nil {
+		return nil, err
+	}
+	return &ExecRunner{Executable: located, Env: c.env}, nil
+}
+
+// logf writes to the logger of the client, if there is one
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.logger != nil {
+		c.logger.Printf("pashua: "+format, args...)
+	}
+}
@@ -0,0 +1,75 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingFS counts the files opened, fs.Stat opens them as well
+type countingFS struct {
+	fs.FS
+	opened int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opened++
+	return c.FS.Open(name)
+}
+
+func TestClientLocatesOnce(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "Pashua")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > /dev/null\necho tf=Wallace\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fsys := &countingFS{FS: os.DirFS("/")}
+	c := NewClient(WithLocator(&Locator{FS: fsys}), WithPath(script))
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{})
+
+	res, err := c.Run(context.Background(), win)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := res.String("tf"); name != "Wallace" {
+		t.Errorf("tf = %q, want Wallace", name)
+	}
+	searched := fsys.opened
+	if searched == 0 {
+		t.Fatal("the locator file system was not used")
+	}
+	if _, err := c.RunConfig(context.Background(), "tf.type=textfield\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Run(context.Background(), win); err != nil {
+		t.Fatal(err)
+	}
+	if located, err := c.Locate(); err != nil || located != script {
+		t.Errorf("Locate = %q, %v, want %q", located, err, script)
+	}
+	if fsys.opened != searched {
+		t.Errorf("%d files opened after the first run, want the location to be cached", fsys.opened-searched)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	script, _ := sleepingPashua(t)
+	c := NewClient(WithPath(script), WithTimeout(200*time.Millisecond))
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{})
+	start := time.Now()
+	_, err := c.Run(context.Background(), win)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("returned after %v, the timeout was not applied", elapsed)
+	}
+	if _, err := c.RunConfig(context.Background(), "tf.type=textfield\n"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("RunConfig: error %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Command fakepashua behaves like "Pashua.app/Contents/MacOS/Pashua -"
+// without showing a window, so code using the binding can be tested
+// end to end on machines without Pashua (e.g. Linux CI).
+//
+// It reads the configuration from stdin (or the file given as argument),
+// rejects configurations it cannot read (syntax errors, unknown element
+// types or attributes, invalid values) with an error on stderr and exit
+// code 1 and otherwise prints the answers to stdout, just like Pashua does.
+// Like Pashua, it does not check whether image files exist or mandatory
+// elements have a label; use PashuaWindow.Validate for such checks.
+//
+// The answers are the defaults of all elements (the default button counts
+// as clicked), overridden by the key=value lines read from the file named
+// in -script or $FAKEPASHUA_SCRIPT and the key=value lines in
+// $FAKEPASHUA_ANSWERS. The mode (-mode or $FAKEPASHUA_MODE) simulates
+// other ways to close the window:
+//
+//	default    the user clicked the default button
+//	cancel     the user clicked the cancel button, all values are empty
+//	autoclose  the window closed after autoclosetime, no button was clicked
+//
+// Point LocatePashua (or RunPashua) at the binary to use it:
+//
+//	res, err := pashua.RunPashua(config, "/path/to/fakepashua")
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	pashua "github.com/derlinkshaender/pashua-binding-go"
+)
+
+func main() {
+	script := flag.String("script", os.Getenv("FAKEPASHUA_SCRIPT"), "file with key=value answers")
+	mode := flag.String("mode", os.Getenv("FAKEPASHUA_MODE"), "default, cancel or autoclose")
+	flag.Parse()
+	if err := run(flag.Arg(0), *script, *mode, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// run reads the configuration and writes the answers
+func run(configFile string, script string, mode string, stdin io.Reader, stdout io.Writer) error {
+	in := stdin
+	if configFile != "" && configFile != "-" {
+		f, err := os.Open(configFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	win, err := pashua.ParseConfig(in)
+	if err != nil {
+		return err
+	}
+	answers := pashua.DefaultAnswers(win)
+	scripted := make(map[string]string)
+	if script != "" {
+		data, err := os.ReadFile(script)
+		if err != nil {
+			return err
+		}
+		for key, value := range pashua.ParsePashuaOutput(string(data)) {
+			scripted[key] = value
+		}
+	}
+	for key, value := range pashua.ParsePashuaOutput(os.Getenv("FAKEPASHUA_ANSWERS")) {
+		scripted[key] = value
+	}
+	for key, value := range scripted {
+		if _, found := win.Elements.Get(key); !found {
+			return fmt.Errorf("answer for unknown element %q", key)
+		}
+		answers[key] = value
+	}
+	switch mode {
+	case "", "default":
+	case "cancel":
+		for _, elem := range win.Elements {
+			if _, found := answers[elem.Key]; !found {
+				continue
+			}
+			answers[elem.Key] = ""
+			if comp, ok := elem.Component.(pashua.Component); ok && comp.PashuaType() == "cancelbutton" {
+				answers[elem.Key] = "1"
+			}
+		}
+	case "autoclose":
+		for _, elem := range win.Elements {
+			if comp, ok := elem.Component.(pashua.Component); ok {
+				switch comp.PashuaType() {
+				case "button", "defaultbutton", "cancelbutton":
+					answers[elem.Key] = "0"
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+	_, err = io.WriteString(stdout, pashua.FormatPashuaOutput(answers))
+	return err
+}
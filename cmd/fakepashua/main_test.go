@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunAcceptsWhatPashuaAccepts(t *testing.T) {
+	config := "img.type=image\nimg.path=/Users/someone/Pictures/logo.png\n" +
+		"tf.type=textfield\ntf.mandatory=1\ntf.default=x\nok.type=defaultbutton\n"
+	var out strings.Builder
+	if err := run("", "", "", strings.NewReader(config), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ok=1\ntf=x\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunRejectsUnreadableConfig(t *testing.T) {
+	for _, config := range []string{"a.type=bogus\n", "a.type=button\na.bogus=1\n", "a.type=checkbox\na.default=maybe\n"} {
+		if err := run("", "", "", strings.NewReader(config), &strings.Builder{}); err == nil {
+			t.Errorf("%q: expected an error", config)
+		}
+	}
+}
+
+func TestRunModes(t *testing.T) {
+	config := "tf.type=textfield\ntf.default=x\nok.type=defaultbutton\nno.type=cancelbutton\n"
+	tests := map[string]string{
+		"cancel":    "no=1\nok=\ntf=\n",
+		"autoclose": "no=0\nok=0\ntf=x\n",
+	}
+	for mode, want := range tests {
+		var out strings.Builder
+		if err := run("", "", mode, strings.NewReader(config), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("mode %s: output = %q, want %q", mode, out.String(), want)
+		}
+	}
+}
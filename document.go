@@ -0,0 +1,405 @@
+package pashua
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Document is the neutral form of a Pashua configuration: the window
+// attributes and the elements in order, each with its key, its type and
+// its attributes in order. Every element struct is converted into a
+// document element, and encoding, parsing and validation work on
+// documents, so they need to know nothing about the single structs
+type Document struct {
+	// Window holds the "*." attributes of the window, e.g. "title"
+	Window []DocumentAttribute
+	// Elements holds the elements in the order they are written
+	Elements []DocumentElement
+}
+
+// DocumentAttribute is an attribute of the window or of an element.
+// Value is the text written to Pashua, before escaping line breaks
+type DocumentAttribute struct {
+	Name  string
+	Value string
+	// Line is the line of the attribute in a parsed configuration, 0 otherwise
+	Line int
+}
+
+// DocumentElement is an element of a Document. Attributes does not
+// contain the type, options are stored as repeated "option" attributes
+type DocumentElement struct {
+	Key        string
+	Type       string
+	Attributes []DocumentAttribute
+	// Line is the line of the type in a parsed configuration, 0 otherwise
+	Line int
+}
+
+// Get returns the value of the first attribute with the given name
+// and whether the element has such an attribute
+func (elem *DocumentElement) Get(name string) (string, bool) {
+	for _, attr := range elem.Attributes {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns the values of all attributes with the given name,
+// e.g. Values("option") returns the options of a popup
+func (elem *DocumentElement) Values(name string) []string {
+	result := []string{}
+	for _, attr := range elem.Attributes {
+		if attr.Name == name {
+			result = append(result, attr.Value)
+		}
+	}
+	return result
+}
+
+// NewDocumentElement converts a component into a document element,
+// encoding the value of each attribute
+func NewDocumentElement(key string, comp Component) (DocumentElement, error) {
+	return documentElement(key, comp, nil)
+}
+
+// documentElement converts a component into a document element.
+// If target is not nil, attributes are checked against its version
+func documentElement(key string, comp Component, target *versionTarget) (DocumentElement, error) {
+	if isNilPointer(comp) {
+		return DocumentElement{}, fmt.Errorf("component %q is a nil pointer", key)
+	}
+	typ := comp.PashuaType()
+	if t := builtinFields(comp); t != nil {
+		return tableElement(key, typ, t, target)
+	}
+	attrs := comp.PashuaAttributes()
+	elem := DocumentElement{Key: key, Type: typ, Attributes: make([]DocumentAttribute, 0, len(attrs))}
+	for _, attr := range attrs {
+		if skip, err := target.skip(key, typ, attr.Name); skip || err != nil {
+			if err != nil {
+				return elem, err
+			}
+			continue
+		}
+		value, err := encodeValue(attr.Value)
+		if err != nil {
+			return elem, fmt.Errorf("element %q: attribute %q: %v", key, attr.Name, err)
+		}
+		elem.Attributes = append(elem.Attributes, DocumentAttribute{Name: attr.Name, Value: value})
+	}
+	return elem, nil
+}
+
+// tableElement converts a built-in element using its attribute table.
+// It writes the same attributes as PashuaAttributes, but reads the
+// fields directly, so options are not boxed one by one
+func tableElement(key string, typ string, t fieldTable, target *versionTarget) (DocumentElement, error) {
+	fields := t.attributeFields()
+	explicit := *t.explicitSet()
+	size := 0
+	for _, f := range fields {
+		if list, ok := f.field.(*[]string); ok {
+			size += len(*list)
+		} else {
+			size++
+		}
+	}
+	elem := DocumentElement{Key: key, Type: typ, Attributes: make([]DocumentAttribute, 0, size)}
+	for _, f := range fields {
+		list, isList := f.field.(*[]string)
+		if isList && len(*list) == 0 || !isList && !f.written(explicit) {
+			continue
+		}
+		if skip, err := target.skip(key, typ, f.name); skip || err != nil {
+			if err != nil {
+				return elem, err
+			}
+			continue
+		}
+		if isList {
+			for _, option := range *list {
+				elem.Attributes = append(elem.Attributes, DocumentAttribute{Name: f.name, Value: option})
+			}
+			continue
+		}
+		value, err := f.text()
+		if err != nil {
+			return elem, fmt.Errorf("element %q: attribute %q: %v", key, f.name, err)
+		}
+		elem.Attributes = append(elem.Attributes, DocumentAttribute{Name: f.name, Value: value})
+	}
+	return elem, nil
+}
+
+// windowAttributes returns the attributes of the window that are set
+func windowAttributes(win *PashuaWindow) []DocumentAttribute {
+	result := []DocumentAttribute{}
+	add := func(name string, value string) {
+		result = append(result, DocumentAttribute{Name: name, Value: value})
+	}
+	if win.Title != "" {
+		add("title", win.Title)
+	}
+	if win.Transparency > 0 {
+		// do not display invisible dialogs ;-)
+		add("transparency", strconv.FormatFloat(win.Transparency, 'f', 4, 64))
+	}
+	if win.AutoCloseTime > 1 {
+		add("autoclosetime", strconv.Itoa(win.AutoCloseTime))
+	}
+	if win.AutoSaveKey != "" {
+		add("autosavekey", win.AutoSaveKey)
+	} else {
+		// without x and y Pashua centers the window
+		if win.X != 0 || win.Explicit.Has("x") {
+			add("x", strconv.Itoa(win.X))
+		}
+		if win.Y != 0 || win.Explicit.Has("y") {
+			add("y", strconv.Itoa(win.Y))
+		}
+	}
+	if win.Floating {
+		add("floating", "1")
+	}
+	return result
+}
+
+// Document converts the window into a Document. Like Encode, it
+// returns an error for components that cannot be encoded
+func (win *PashuaWindow) Document() (*Document, error) {
+	doc := &Document{Window: windowAttributes(win)}
+	err := win.documentElements(false, nil, func(elem DocumentElement) error {
+		doc.Elements = append(doc.Elements, elem)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// documentElements converts the components of the window one by one
+// and passes them to f. Components that cannot be converted are
+// skipped if skipUnknown is set, otherwise their error is returned.
+// The same holds for Components entries whose key is used in Elements
+func (win *PashuaWindow) documentElements(skipUnknown bool, target *versionTarget, f func(DocumentElement) error) error {
+	if key, found := win.sharedKey(); found && !skipUnknown {
+		return fmt.Errorf("component %q is used in both Elements and Components", key)
+	}
+	for _, elem := range win.allElements() {
+		comp, ok := elem.Component.(Component)
+		if !ok {
+			if skipUnknown {
+				continue
+			}
+			return fmt.Errorf("component %q of type %T does not implement pashua.Component", elem.Key, elem.Component)
+		}
+		docElem, err := documentElement(elem.Key, comp, target)
+		if err != nil {
+			if skipUnknown {
+				continue
+			}
+			return err
+		}
+		if err := f(docElem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo writes the document as Pashua configuration to w.
+// Values are escaped; keys, types and attribute names must be valid
+// names, so that no input can add extra config lines
+func (doc *Document) WriteTo(w io.Writer) (int64, error) {
+	cw := &configWriter{w: w, buf: make([]byte, 0, flushSize)}
+	if err := cw.window(doc.Window); err != nil {
+		return cw.written, err
+	}
+	for _, elem := range doc.Elements {
+		if err := cw.element(elem); err != nil {
+			return cw.written, err
+		}
+	}
+	return cw.written, cw.flush()
+}
+
+// flushSize is the amount of buffered config data written at once
+const flushSize = 4096
+
+// configWriter writes config lines to w, collecting them in a buffer
+// that is written whenever it holds flushSize bytes
+type configWriter struct {
+	w       io.Writer
+	buf     []byte
+	written int64
+}
+
+// window adds the "*." lines of the window attributes
+func (cw *configWriter) window(attrs []DocumentAttribute) error {
+	for _, attr := range attrs {
+		if !validKey.MatchString(attr.Name) {
+			return fmt.Errorf("invalid window attribute name %q", attr.Name)
+		}
+		cw.buf = appendLine(cw.newline(), "*", attr.Name, attr.Value)
+	}
+	return nil
+}
+
+// element adds the lines of an element, writing them whenever the
+// buffer is full. If the element is invalid, nothing is added and the
+// error is returned
+func (cw *configWriter) element(elem DocumentElement) error {
+	if err := checkElement(elem); err != nil {
+		return err
+	}
+	cw.buf = appendLine(cw.newline(), elem.Key, "type", elem.Type)
+	for _, attr := range elem.Attributes {
+		if len(cw.buf) >= flushSize {
+			if err := cw.flush(); err != nil {
+				return err
+			}
+		}
+		cw.buf = appendLine(append(cw.buf, '\n'), elem.Key, attr.Name, attr.Value)
+	}
+	if len(cw.buf) >= flushSize {
+		return cw.flush()
+	}
+	return nil
+}
+
+// newline returns the buffer with a line break appended,
+// unless it is the start of the configuration
+func (cw *configWriter) newline() []byte {
+	if cw.written > 0 || len(cw.buf) > 0 {
+		return append(cw.buf, '\n')
+	}
+	return cw.buf
+}
+
+// flush writes the buffered lines to w
+func (cw *configWriter) flush() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	n, err := cw.w.Write(cw.buf)
+	cw.written += int64(n)
+	cw.buf = cw.buf[:0]
+	return err
+}
+
+// appendElement appends the lines of an element to buf
+func appendElement(buf []byte, elem DocumentElement) ([]byte, error) {
+	if err := checkElement(elem); err != nil {
+		return buf, err
+	}
+	buf = appendLine(buf, elem.Key, "type", elem.Type)
+	for _, attr := range elem.Attributes {
+		buf = appendLine(append(buf, '\n'), elem.Key, attr.Name, attr.Value)
+	}
+	return buf, nil
+}
+
+// checkElement returns an error if the key, the type or an attribute
+// name of the element is not a valid name
+func checkElement(elem DocumentElement) error {
+	if !validKey.MatchString(elem.Key) {
+		return fmt.Errorf("invalid element key %q", elem.Key)
+	}
+	if !validKey.MatchString(elem.Type) {
+		return fmt.Errorf("element %q: invalid type %q", elem.Key, elem.Type)
+	}
+	checked := ""
+	for _, attr := range elem.Attributes {
+		// repeated attributes such as options follow each other,
+		// so each name is usually checked once
+		if attr.Name == checked {
+			continue
+		}
+		if !validKey.MatchString(attr.Name) {
+			return fmt.Errorf("element %q: invalid attribute name %q", elem.Key, attr.Name)
+		}
+		checked = attr.Name
+	}
+	return nil
+}
+
+// appendLine appends a single "key.name=value" line without line break
+func appendLine(buf []byte, key string, name string, value string) []byte {
+	buf = append(buf, key...)
+	buf = append(buf, '.')
+	buf = append(buf, name...)
+	buf = append(buf, '=')
+	return append(buf, escapeValue(value)...)
+}
+
+// ParseDocument reads a Pashua configuration into a Document. The
+// attributes of an element are collected in the order of the lines,
+// elements are ordered by the line in which they appear first.
+// Empty lines and lines starting with "#" are skipped, "[return]" in
+// values is converted to a newline. Types and attribute names are not
+// checked, use ParseConfig to get a PashuaWindow of known elements
+func ParseDocument(r io.Reader) (*Document, error) {
+	doc := &Document{}
+	index := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		pos := strings.Index(line, "=")
+		if pos < 0 {
+			return nil, &ParseError{number, fmt.Sprintf("missing \"=\" in %q", trimmed)}
+		}
+		name := strings.TrimSpace(line[:pos])
+		value := unescapeValue(line[pos+1:])
+		dot := strings.Index(name, ".")
+		if dot <= 0 || dot == len(name)-1 {
+			return nil, &ParseError{number, fmt.Sprintf("invalid element name %q, expected key.attribute", name)}
+		}
+		key, attr := name[:dot], strings.ToLower(name[dot+1:])
+		if key == "*" {
+			doc.Window = append(doc.Window, DocumentAttribute{Name: attr, Value: value, Line: number})
+			continue
+		}
+		i, found := index[key]
+		if !found {
+			i = len(doc.Elements)
+			index[key] = i
+			doc.Elements = append(doc.Elements, DocumentElement{Key: key})
+		}
+		elem := &doc.Elements[i]
+		if attr == "type" {
+			if elem.Type != "" {
+				return nil, &ParseError{number, fmt.Sprintf("duplicate type for element %q", key)}
+			}
+			elem.Type, elem.Line = strings.TrimSpace(value), number
+			continue
+		}
+		elem.Attributes = append(elem.Attributes, DocumentAttribute{Name: attr, Value: value, Line: number})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, elem := range doc.Elements {
+		if elem.Type == "" {
+			line := 0
+			if len(elem.Attributes) > 0 {
+				line = elem.Attributes[0].Line
+			}
+			return nil, &ParseError{line, fmt.Sprintf("element %q has no type", elem.Key)}
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,70 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrPashuaNotFound is matched by the error returned if the
+	// Pashua executable could not be located
+	ErrPashuaNotFound = errors.New("pashua not found")
+	// ErrCancelled is matched by the error returned if the context
+	// was cancelled while the dialog was open
+	ErrCancelled = errors.New("pashua cancelled")
+	// ErrTimeout is matched by the error returned if the deadline of the
+	// context (or the timeout of the client) passed while the dialog was open
+	ErrTimeout = errors.New("pashua timed out")
+)
+
+// Is reports that a NotFoundError matches ErrPashuaNotFound
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrPashuaNotFound
+}
+
+// ExitError is returned if Pashua exits with an error,
+// for example because it rejected the configuration
+type ExitError struct {
+	// Code is the exit code of Pashua, -1 if it could not be started
+	Code int
+	// Stderr is the error text written by Pashua
+	Stderr string
+	// Config is the configuration passed to Pashua
+	Config string
+	// Err is the underlying error from os/exec
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("Error: %v, pashua error text: %s", e.Err, e.Stderr)
+}
+
+// Unwrap returns the underlying error from os/exec
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// stoppedError is returned if Pashua was stopped because the context is
+// done. It matches ErrCancelled or ErrTimeout as well as the context error
+type stoppedError struct {
+	reason error
+	err    error
+}
+
+func (e *stoppedError) Error() string {
+	return "pashua stopped: " + e.err.Error()
+}
+
+func (e *stoppedError) Unwrap() []error {
+	return []error{e.reason, e.err}
+}
+
+// newStoppedError wraps the error of a context that is done
+func newStoppedError(ctxErr error) error {
+	reason := ErrCancelled
+	if errors.Is(ctxErr, context.DeadlineExceeded) {
+		reason = ErrTimeout
+	}
+	return &stoppedError{reason: reason, err: ctxErr}
+}
@@ -16,24 +16,26 @@ func main() {
 	// define a window to show some of the components
 	cfg := pashua.PashuaWindow{
 		Title:        "Dialog Box",
-		AutoSaveKey: "hurga",
+		AutoSaveKey:  "hurga",
 		Transparency: 1,
-		Components: pashua.PashuaComponents{
-			"tf": pashua.PashuaTextField{
+		// Elements keeps the order in which the components are
+		// defined, so Pashua lays them out top to bottom as listed here
+		Elements: pashua.PashuaElements{
+			{Key: "tf", Component: pashua.PashuaTextField{
 				Label:   "Gib was ein",
 				Default: "42",
 				Width:   100,
 				Y:       20,
-			},
-			"cb": pashua.PashuaCombobox{
+			}},
+			{Key: "cb", Component: pashua.PashuaCombobox{
 				Label:          "My combobox label",
 				Option:         []string{"Gromit", "Wallace", "Harold", "Maude"},
 				Width:          220,
 				Tooltip:        "Choose from the list",
 				CompletionMode: pashua.CaseInsensitive,
 				Y:              60,
-			},
-			"dt": pashua.PashuaDate{
+			}},
+			{Key: "dt", Component: pashua.PashuaDate{
 				Label:   "TickTock",
 				Tooltip: "A Date/Time control",
 				UseDate: true,
@@ -41,15 +43,15 @@ func main() {
 				Default: "2020-07-04",
 				Textual: false,
 				Y:       120,
-			},
-			"ok": pashua.PashuaDefaultButton{
+			}},
+			{Key: "ok", Component: pashua.PashuaDefaultButton{
 				Label:   "OK",
 				Tooltip: "Click here to submit dialog",
-			},
-			"cancel": pashua.PashuaCancelButton{
+			}},
+			{Key: "cancel", Component: pashua.PashuaCancelButton{
 				Label:   "Cancel",
 				Tooltip: "",
-			},
+			}},
 		},
 	}
 
@@ -73,4 +75,3 @@ func main() {
 	}
 	fmt.Println("Done.")
 }
-
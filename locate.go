@@ -0,0 +1,281 @@
+package pashua
+
+import (
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// bundlePath is the location of the executable within Pashua.app
+const bundlePath = "Pashua.app/Contents/MacOS/Pashua"
+
+// SearchFunc returns additional paths where Pashua might be found.
+// A path may point to the executable, to Pashua.app or to a
+// directory containing either of them
+type SearchFunc func() []string
+
+var (
+	searchFuncsMu sync.Mutex
+	searchFuncs   []SearchFunc
+)
+
+// RegisterSearchFunc adds a function that is asked for additional
+// locations whenever Pashua is searched. The locations are checked
+// after the explicit path, $PASHUA_PATH and the search directories
+// of the client, but before the standard locations
+func RegisterSearchFunc(f SearchFunc) {
+	searchFuncsMu.Lock()
+	defer searchFuncsMu.Unlock()
+	searchFuncs = append(searchFuncs, f)
+}
+
+// Reasons why a candidate path was accepted or rejected
+const (
+	CandidateFound         = "found"
+	CandidateMissing       = "missing"
+	CandidateDirectory     = "directory"
+	CandidateNotExecutable = "not executable"
+)
+
+// Candidate is a path that was checked while locating Pashua,
+// Reason is one of the Candidate* constants
+type Candidate struct {
+	Path   string
+	Reason string
+}
+
+// NotFoundError is returned if Pashua could not be located
+// and lists all locations that were tried
+type NotFoundError struct {
+	Tried      []string
+	Candidates []Candidate
+}
+
+func (e *NotFoundError) Error() string {
+	tried := make([]string, 0, len(e.Candidates))
+	for _, c := range e.Candidates {
+		tried = append(tried, c.Path+" ("+c.Reason+")")
+	}
+	return "Could not locate pashua, tried: " + strings.Join(tried, ", ")
+}
+
+// Locator searches the Pashua executable. All access to the system
+// goes through its fields, so the search can be tested with an
+// in-memory file system such as fstest.MapFS. Nil fields use the
+// real system: the root file system, the home directory of the current
+// user, the environment and the path of the running program
+type Locator struct {
+	// FS is the file system searched, absolute paths are looked up without the leading "/"
+	FS fs.FS
+	// HomeDir returns the home directory of the current user
+	HomeDir func() (string, error)
+	// Getenv returns the value of an environment variable
+	Getenv func(key string) string
+	// Executable returns the path of the running program
+	Executable func() (string, error)
+	// SearchDirs are searched before the standard locations
+	SearchDirs []string
+}
+
+// homeDir returns the home directory from $HOME or the user database
+func homeDir() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return usr.HomeDir, nil
+}
+
+// resolve returns the file system of the locator and the name
+// of an absolute or relative path within it
+func (l *Locator) resolve(name string) (fs.FS, string, error) {
+	fsys := l.FS
+	if fsys == nil {
+		fsys = os.DirFS("/")
+	}
+	if !path.IsAbs(name) {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, "", err
+		}
+		name = filepath.ToSlash(abs)
+	}
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	if name == "" {
+		name = "."
+	}
+	return fsys, name, nil
+}
+
+// stat looks up an absolute or relative path in the file system of the locator
+func (l *Locator) stat(name string) (fs.FileInfo, error) {
+	fsys, name, err := l.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(fsys, name)
+}
+
+// readFile reads a file from the file system of the locator
+func (l *Locator) readFile(name string) ([]byte, error) {
+	fsys, name, err := l.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(fsys, name)
+}
+
+// getenv returns an environment variable using Getenv or os.Getenv
+func (l *Locator) getenv(key string) string {
+	if l.Getenv != nil {
+		return l.Getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+// expand turns a location into the paths that are checked for it:
+// Pashua.app is completed to the executable within the bundle and
+// a directory is searched for Pashua and Pashua.app
+func (l *Locator) expand(location string) []string {
+	if strings.HasSuffix(strings.TrimSuffix(location, "/"), ".app") {
+		return []string{path.Join(location, "Contents/MacOS/Pashua")}
+	}
+	if info, err := l.stat(location); err == nil && info.IsDir() {
+		return []string{path.Join(location, "Pashua"), path.Join(location, bundlePath)}
+	}
+	return []string{location}
+}
+
+// appBundleDirs returns the Resources and Helpers directories of the
+// app bundle containing the running program, if there is one
+func appBundleDirs(exe string) []string {
+	macOS := path.Dir(exe)
+	contents := path.Dir(macOS)
+	if path.Base(macOS) != "MacOS" || path.Base(contents) != "Contents" ||
+		!strings.HasSuffix(path.Dir(contents), ".app") {
+		return nil
+	}
+	return []string{path.Join(contents, "Resources"), path.Join(contents, "Helpers")}
+}
+
+// inDirs returns the paths of Pashua and Pashua.app in each directory
+func inDirs(dirs []string) []string {
+	result := make([]string, 0, 2*len(dirs))
+	for _, dir := range dirs {
+		result = append(result, path.Join(dir, "Pashua"), path.Join(dir, bundlePath))
+	}
+	return result
+}
+
+// locations returns all locations to search for Pashua, in order
+func (l *Locator) locations(pashuaPath string) []string {
+	locations := []string{}
+	if pashuaPath != "" {
+		locations = append(locations, pashuaPath)
+	}
+	if env := l.getenv("PASHUA_PATH"); env != "" {
+		locations = append(locations, env)
+	}
+	locations = append(locations, inDirs(l.SearchDirs)...)
+	searchFuncsMu.Lock()
+	for _, f := range searchFuncs {
+		locations = append(locations, f()...)
+	}
+	searchFuncsMu.Unlock()
+	executable := l.Executable
+	if executable == nil {
+		executable = os.Executable
+	}
+	if exe, err := executable(); err == nil {
+		exe = filepath.ToSlash(exe)
+		locations = append(locations, inDirs([]string{path.Dir(exe)})...)
+		locations = append(locations, inDirs(appBundleDirs(exe))...)
+	}
+	locations = append(locations,
+		"./"+bundlePath,
+		path.Join("/Applications", bundlePath),
+	)
+	home := l.HomeDir
+	if home == nil {
+		home = homeDir
+	}
+	// a missing home directory (e.g. in minimal containers) is not an error
+	if usrhome, err := home(); err == nil && usrhome != "" {
+		locations = append(locations, path.Join(usrhome, "Applications", bundlePath))
+	}
+	for _, dir := range filepath.SplitList(l.getenv("PATH")) {
+		if dir != "" {
+			locations = append(locations, path.Join(dir, "Pashua"), path.Join(dir, "pashua"))
+		}
+	}
+	locations = append(locations,
+		"/opt/homebrew/bin/Pashua",
+		path.Join("/opt/homebrew/bin", bundlePath),
+		path.Join("/usr/local/bin", bundlePath),
+	)
+	return locations
+}
+
+// check returns why the path can or cannot be used as Pashua executable
+func (l *Locator) check(name string) string {
+	info, err := l.stat(name)
+	switch {
+	case err != nil:
+		return CandidateMissing
+	case info.IsDir():
+		return CandidateDirectory
+	case info.Mode().Perm()&0111 == 0:
+		return CandidateNotExecutable
+	}
+	return CandidateFound
+}
+
+// Candidates checks all locations in search order, stopping at the first
+// executable found, and returns every path checked with the reason
+func (l *Locator) Candidates(pashuaPath string) []Candidate {
+	result := []Candidate{}
+	seen := make(map[string]bool)
+	for _, location := range l.locations(pashuaPath) {
+		for _, p := range l.expand(location) {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			reason := l.check(p)
+			result = append(result, Candidate{Path: p, Reason: reason})
+			if reason == CandidateFound {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// Locate searches pashuaPath, $PASHUA_PATH, the search directories,
+// the registered search functions and the standard file locations,
+// in this order, and returns the first executable found.
+// If there is none, the error is a *NotFoundError
+func (l *Locator) Locate(pashuaPath string) (string, error) {
+	candidates := l.Candidates(pashuaPath)
+	if n := len(candidates); n > 0 && candidates[n-1].Reason == CandidateFound {
+		return candidates[n-1].Path, nil
+	}
+	tried := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		tried = append(tried, c.Path)
+	}
+	return "", &NotFoundError{Tried: tried, Candidates: candidates}
+}
+
+// locatePashua searches Pashua using a Locator on the real system
+func locatePashua(pashuaPath string, searchDirs []string) (string, error) {
+	l := &Locator{SearchDirs: searchDirs}
+	return l.Locate(pashuaPath)
+}
@@ -0,0 +1,138 @@
+package pashua
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// executable is a file entry with the exec bit set
+var executable = &fstest.MapFile{Mode: 0755}
+
+// testLocator returns a Locator that only sees files and env
+func testLocator(files fstest.MapFS, env map[string]string) *Locator {
+	return &Locator{
+		FS:         files,
+		HomeDir:    func() (string, error) { return "/Users/me", nil },
+		Getenv:     func(key string) string { return env[key] },
+		Executable: func() (string, error) { return "/work/bin/tool", nil },
+	}
+}
+
+func TestLocateOrder(t *testing.T) {
+	files := fstest.MapFS{
+		"Applications/Pashua.app/Contents/MacOS/Pashua":          executable,
+		"Users/me/Applications/Pashua.app/Contents/MacOS/Pashua": executable,
+		"env/Pashua.app/Contents/MacOS/Pashua":                   executable,
+		"dirs/Pashua":                                            executable,
+		"work/bin/Pashua":                                        executable,
+		"explicit/Pashua":                                        executable,
+		"path/pashua":                                            executable,
+	}
+	tests := []struct {
+		path   string
+		env    map[string]string
+		dirs   []string
+		remove []string
+		want   string
+	}{
+		{path: "/explicit/Pashua", env: map[string]string{"PASHUA_PATH": "/env/Pashua.app"}, want: "/explicit/Pashua"},
+		{path: "/explicit", want: "/explicit/Pashua"},
+		{env: map[string]string{"PASHUA_PATH": "/env/Pashua.app"}, dirs: []string{"/dirs"}, want: "/env/Pashua.app/Contents/MacOS/Pashua"},
+		{dirs: []string{"/dirs"}, want: "/dirs/Pashua"},
+		{want: "/work/bin/Pashua"},
+		{remove: []string{"work/bin/Pashua"}, want: "/Applications/Pashua.app/Contents/MacOS/Pashua"},
+		{remove: []string{"work/bin/Pashua", "Applications/Pashua.app/Contents/MacOS/Pashua"}, want: "/Users/me/Applications/Pashua.app/Contents/MacOS/Pashua"},
+		{
+			remove: []string{"work/bin/Pashua", "Applications/Pashua.app/Contents/MacOS/Pashua", "Users/me/Applications/Pashua.app/Contents/MacOS/Pashua"},
+			env:    map[string]string{"PATH": "/nowhere:/path"},
+			want:   "/path/pashua",
+		},
+	}
+	for i, test := range tests {
+		fsys := fstest.MapFS{}
+		for name, file := range files {
+			fsys[name] = file
+		}
+		for _, name := range test.remove {
+			delete(fsys, name)
+		}
+		l := testLocator(fsys, test.env)
+		l.SearchDirs = test.dirs
+		got, err := l.Locate(test.path)
+		if err != nil || got != test.want {
+			t.Errorf("test %d: Locate = %q, %v, want %q", i, got, err, test.want)
+		}
+	}
+}
+
+func TestLocateCandidateReasons(t *testing.T) {
+	files := fstest.MapFS{
+		"a/Pashua/Pashua": &fstest.MapFile{Mode: fs.ModeDir | 0755},
+		"b/Pashua":        &fstest.MapFile{Mode: 0644},
+		"c/Pashua":        executable,
+		"d/not-checked":   executable,
+	}
+	l := testLocator(files, nil)
+	l.SearchDirs = []string{"/a", "/b", "/missing", "/c", "/d"}
+	got := l.Candidates("")
+	want := []Candidate{
+		{"/a/Pashua/Pashua", CandidateDirectory},
+		{"/a/Pashua/Pashua.app/Contents/MacOS/Pashua", CandidateMissing},
+		{"/a/Pashua.app/Contents/MacOS/Pashua", CandidateMissing},
+		{"/b/Pashua", CandidateNotExecutable},
+		{"/b/Pashua.app/Contents/MacOS/Pashua", CandidateMissing},
+		{"/missing/Pashua", CandidateMissing},
+		{"/missing/Pashua.app/Contents/MacOS/Pashua", CandidateMissing},
+		{"/c/Pashua", CandidateFound},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Candidates =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLocateNotFound(t *testing.T) {
+	l := testLocator(fstest.MapFS{}, nil)
+	l.HomeDir = func() (string, error) { return "", errors.New("no home directory") }
+	_, err := l.Locate("")
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrPashuaNotFound) {
+		t.Fatalf("error %v is not a *NotFoundError", err)
+	}
+	for _, c := range notFound.Candidates {
+		if c.Reason != CandidateMissing {
+			t.Errorf("candidate %v, want only missing candidates", c)
+		}
+		if c.Path == "/Users/me/Applications/Pashua.app/Contents/MacOS/Pashua" {
+			t.Errorf("home directory searched although it could not be resolved")
+		}
+	}
+	if len(notFound.Tried) != len(notFound.Candidates) {
+		t.Errorf("Tried has %d entries, Candidates %d", len(notFound.Tried), len(notFound.Candidates))
+	}
+}
+
+func TestLocateInAppBundle(t *testing.T) {
+	files := fstest.MapFS{"Apps/Tool.app/Contents/Helpers/Pashua.app/Contents/MacOS/Pashua": executable}
+	l := testLocator(files, nil)
+	l.Executable = func() (string, error) { return "/Apps/Tool.app/Contents/MacOS/tool", nil }
+	got, err := l.Locate("")
+	if want := "/Apps/Tool.app/Contents/Helpers/Pashua.app/Contents/MacOS/Pashua"; err != nil || got != want {
+		t.Errorf("Locate = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestClientRunsLocatedExecutable(t *testing.T) {
+	files := fstest.MapFS{"injected/Pashua": executable}
+	c := NewClient(WithLocator(testLocator(files, nil)), WithPath("/injected"))
+	runner, err := c.getRunner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, ok := runner.(*ExecRunner)
+	if !ok || exec.Executable != "/injected/Pashua" {
+		t.Errorf("runner = %#v, want an ExecRunner for /injected/Pashua", runner)
+	}
+}
@@ -0,0 +1,186 @@
+package pashua
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ParseError is returned by ParseConfig and ParseDocument and describes
+// the line of the configuration that could not be parsed
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseConfig reads a Pashua configuration and converts it
+// into a PashuaWindow. The elements are stored in the Elements
+// list of the window in the order in which they appear first.
+// Empty lines and lines starting with "#" are skipped,
+// "[return]" in values is converted to a newline
+func ParseConfig(r io.Reader) (*PashuaWindow, error) {
+	doc, err := ParseDocument(r)
+	if err != nil {
+		return nil, err
+	}
+	win := &PashuaWindow{}
+	for _, attr := range doc.Window {
+		if err := setWindowAttribute(win, attr.Name, attr.Value); err != nil {
+			return nil, &ParseError{attr.Line, err.Error()}
+		}
+	}
+	for _, elem := range doc.Elements {
+		comp, err := parseComponent(elem)
+		if err != nil {
+			return nil, err
+		}
+		win.Elements.Add(elem.Key, comp)
+	}
+	return win, nil
+}
+
+// parseComponent creates the component for the type of a document
+// element and sets all attributes on it
+func parseComponent(elem DocumentElement) (interface{}, error) {
+	comp := newComponent(elem.Type)
+	if comp == nil {
+		return nil, &ParseError{elem.Line, fmt.Sprintf("unknown type %q for element %q", elem.Type, elem.Key)}
+	}
+	fields := componentFields(comp)
+	for _, attr := range elem.Attributes {
+		field, found := fields[attr.Name]
+		if !found {
+			return nil, &ParseError{attr.Line, fmt.Sprintf("unknown attribute %q for element %q", attr.Name, elem.Key)}
+		}
+		if err := setField(field, attr.Value); err != nil {
+			return nil, &ParseError{attr.Line, fmt.Sprintf("attribute %q of element %q: %v", attr.Name, elem.Key, err)}
+		}
+		keepZero(comp, attr.Name, field)
+	}
+	// store the component as a value, just like a hand-written window would
+	return reflect.ValueOf(comp).Elem().Interface(), nil
+}
+
+// newComponent returns a pointer to a new component of the given Pashua type
+// or nil if the type is unknown
+func newComponent(typ string) fieldTable {
+	switch typ {
+	case "button":
+		return &PashuaButton{}
+	case "cancelbutton":
+		return &PashuaCancelButton{}
+	case "checkbox":
+		return &PashuaCheckbox{}
+	case "combobox":
+		return &PashuaCombobox{}
+	case "date":
+		return &PashuaDate{}
+	case "defaultbutton":
+		return &PashuaDefaultButton{}
+	case "image":
+		return &PashuaImage{}
+	case "openbrowser":
+		return &PashuaOpenBrowser{}
+	case "password":
+		return &PashuaPassword{}
+	case "popup":
+		return &PashuaPopup{}
+	case "radiobutton":
+		return &PashuaRadioButton{}
+	case "savebrowser":
+		return &PashuaSaveBrowser{}
+	case "text":
+		return &PashuaText{}
+	case "textbox":
+		return &PashuaTextBox{}
+	case "textfield":
+		return &PashuaTextField{}
+	}
+	return nil
+}
+
+// componentFields maps the attribute names Pashua uses to pointers to
+// the corresponding fields of a component, taken from its attribute table
+func componentFields(comp fieldTable) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, f := range comp.attributeFields() {
+		fields[f.name] = f.field
+	}
+	return fields
+}
+
+// setWindowAttribute sets a "*.attribute" value on the window
+func setWindowAttribute(win *PashuaWindow, attr string, value string) error {
+	fields := map[string]interface{}{
+		"autoclosetime": &win.AutoCloseTime,
+		"autosavekey":   &win.AutoSaveKey,
+		"floating":      &win.Floating,
+		"title":         &win.Title,
+		"transparency":  &win.Transparency,
+		"x":             &win.X,
+		"y":             &win.Y,
+	}
+	field, found := fields[attr]
+	if !found {
+		return fmt.Errorf("unknown window attribute %q", attr)
+	}
+	if err := setField(field, value); err != nil {
+		return fmt.Errorf("window attribute %q: %v", attr, err)
+	}
+	keepZero(win, attr, field)
+	return nil
+}
+
+// keepZero adds an attribute that was set to its zero value to the
+// Explicit set of the component or window ptr points to, so that it
+// is written again when encoding
+func keepZero(ptr interface{ explicitSet() *AttributeSet }, attr string, field interface{}) {
+	if !reflect.ValueOf(field).Elem().IsZero() {
+		return
+	}
+	explicit := ptr.explicitSet()
+	if *explicit == nil {
+		*explicit = make(AttributeSet)
+	}
+	(*explicit)[attr] = true
+}
+
+// setField converts the string value from the configuration
+// to the type of the field and stores it. Options are appended
+func setField(field interface{}, value string) error {
+	var err error
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *[]string:
+		*f = append(*f, value)
+	case *bool:
+		*f, err = strconv.ParseBool(strings.TrimSpace(value))
+	case *int:
+		*f, err = strconv.Atoi(strings.TrimSpace(value))
+	case *float64:
+		*f, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+	case *CompletionMode:
+		*f = CompletionMode(strings.TrimSpace(value))
+	case *FontSize:
+		*f = FontSize(strings.TrimSpace(value))
+	case *fixedFont:
+		*f = fixedFont(strings.TrimSpace(value) == "fixed")
+	case *alwaysBool:
+		var b bool
+		b, err = strconv.ParseBool(strings.TrimSpace(value))
+		*f = alwaysBool(b)
+	default:
+		err = fmt.Errorf("unsupported field type %T", field)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q", value)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package pashua
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `# a hand-written configuration
+*.title=Settings
+*.floating=1
+
+name.type=textfield
+name.label=Name
+name.default=Line 1[return]Line 2
+name.mandatory=1
+
+kind.type=popup
+kind.label=Kind
+kind.option=small
+kind.option=medium
+kind.option=large
+kind.default=medium
+
+tb.type=textbox
+tb.fonttype=fixed
+tb.width=300
+
+ok.type=defaultbutton
+`
+
+func TestParseConfig(t *testing.T) {
+	win, err := ParseConfig(strings.NewReader(strings.Replace(sampleConfig, "\n", "\r\n", -1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if win.Title != "Settings" || !win.Floating {
+		t.Errorf("window attributes = %q, %v", win.Title, win.Floating)
+	}
+	keys := []string{}
+	for _, elem := range win.Elements {
+		keys = append(keys, elem.Key)
+	}
+	if want := []string{"name", "kind", "tb", "ok"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	want := []interface{}{
+		PashuaTextField{Label: "Name", Default: "Line 1\nLine 2", Mandatory: true},
+		PashuaPopup{Label: "Kind", Option: []string{"small", "medium", "large"}, Default: "medium"},
+		PashuaTextBox{FixedFont: true, Width: 300},
+		PashuaDefaultButton{},
+	}
+	for i, elem := range win.Elements {
+		if !reflect.DeepEqual(elem.Component, want[i]) {
+			t.Errorf("%s = %#v, want %#v", elem.Key, elem.Component, want[i])
+		}
+	}
+}
+
+func TestParseConfigRoundTrip(t *testing.T) {
+	win, err := ParseConfig(strings.NewReader(sampleConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := win.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(win, again) {
+		t.Errorf("round trip changed the window:\n%s", config)
+	}
+}
+
+func TestParseConfigKeepsExplicitZero(t *testing.T) {
+	win, err := ParseConfig(strings.NewReader("*.x=0\ncb.type=checkbox\ncb.default=0\ncb.x=0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := win.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "*.x=0\ncb.type=checkbox\ncb.default=0\ncb.x=0"; config != want {
+		t.Errorf("config = %q, want %q", config, want)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		line   int
+		msg    string
+	}{
+		{"a.type=button\nmissing equals\n", 2, `missing "="`},
+		{"a.type=button\nnodot=1\n", 2, "invalid element name"},
+		{"a.type=bogus\n", 1, `unknown type "bogus"`},
+		{"a.type=button\n\na.bogus=1\n", 3, `unknown attribute "bogus"`},
+		{"a.type=button\na.type=text\n", 2, "duplicate type"},
+		{"a.label=x\n", 1, "has no type"},
+		{"a.type=checkbox\na.default=maybe\n", 2, `attribute "default"`},
+		{"*.bogus=1\n", 1, "unknown window attribute"},
+	}
+	for _, test := range tests {
+		_, err := ParseConfig(strings.NewReader(test.config))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: error %v is not a *ParseError", test.config, err)
+			continue
+		}
+		if parseErr.Line != test.line || !strings.Contains(parseErr.Msg, test.msg) {
+			t.Errorf("%q: got line %d %q, want line %d containing %q", test.config, parseErr.Line, parseErr.Msg, test.line, test.msg)
+		}
+	}
+}
+
+func TestAttributeTablesCoverAllFields(t *testing.T) {
+	for _, typ := range elementTypes {
+		comp := newComponent(typ)
+		fillFields(comp, "value")
+		if textBox, ok := comp.(*PashuaTextBox); ok {
+			textBox.FontSize = Small
+		}
+		rv := reflect.ValueOf(comp).Elem()
+		win := &PashuaWindow{}
+		win.Elements.Add("elem", rv.Interface())
+		config, err := win.Encode()
+		if err != nil {
+			t.Fatalf("%s: %v", typ, err)
+		}
+		parsed, err := ParseConfig(strings.NewReader(config))
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", typ, err, config)
+		}
+		got, _ := parsed.Elements.Get("elem")
+		if !reflect.DeepEqual(got, rv.Interface()) {
+			t.Errorf("%s: a field is missing from the attribute table:\n%#v\n%s", typ, got, config)
+		}
+	}
+}
@@ -1,14 +1,16 @@
 package pashua
 
 import (
-	"bytes"
+	"context"
+	"encoding"
 	"fmt"
-	"os"
-	"os/exec"
-	"os/user"
-	"path"
+	"io"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // CompletionMode is a type to store the completion mode for a combobox
@@ -34,8 +36,11 @@ type PashuaButton struct {
 	Label    string
 	X        int
 	Y        int
+	RelX     int
+	RelY     int
 	Disabled bool
 	Tooltip  string
+	Explicit AttributeSet
 }
 
 // PashuaCancelButton is a structure that holds all information for a PashuaCancelButton
@@ -43,6 +48,7 @@ type PashuaCancelButton struct {
 	Label    string
 	Disabled bool
 	Tooltip  string
+	Explicit AttributeSet
 }
 
 // PashuaCheckbox is a structure that holds all information for a PashuaCheckbox
@@ -55,12 +61,14 @@ type PashuaCheckbox struct {
 	Y        int
 	RelX     int
 	RelY     int
+	Explicit AttributeSet
 }
 
 // PashuaCombobox is a structure that holds all information for a PashuaCombobox
 type PashuaCombobox struct {
 	Label          string
 	Option         []string
+	Default        string
 	CompletionMode CompletionMode
 	Mandatory      bool
 	Rows           int
@@ -72,6 +80,7 @@ type PashuaCombobox struct {
 	Y              int
 	RelX           int
 	RelY           int
+	Explicit       AttributeSet
 }
 
 // PashuaDate is a structure that holds all information for a PashuaDate
@@ -85,6 +94,9 @@ type PashuaDate struct {
 	Tooltip  string
 	X        int
 	Y        int
+	RelX     int
+	RelY     int
+	Explicit AttributeSet
 }
 
 // PashuaDefaultButton is a structure that holds all information for a PashuaDefaultButton
@@ -92,6 +104,7 @@ type PashuaDefaultButton struct {
 	Label    string
 	Disabled bool
 	Tooltip  string
+	Explicit AttributeSet
 }
 
 // PashuaImage is a structure that holds all information for a PashuaImage
@@ -109,6 +122,7 @@ type PashuaImage struct {
 	Y         int
 	RelX      int
 	RelY      int
+	Explicit  AttributeSet
 }
 
 // PashuaOpenBrowser is a structure that holds all information for a PashuaOpenBrowser
@@ -119,24 +133,28 @@ type PashuaOpenBrowser struct {
 	Filetype    string
 	Placeholder string
 	Mandatory   bool
+	Tooltip     string
 	X           int
 	Y           int
 	RelX        int
 	RelY        int
+	Explicit    AttributeSet
 }
 
 // PashuaPassword is a structure that holds all information for a PashuaPassword
 type PashuaPassword struct {
-	Label     string
-	Default   bool
-	Disabled  bool
-	Mandatory bool
-	Tooltip   string
-	Width     int
-	X         int
-	Y         int
-	RelX      int
-	RelY      int
+	Label       string
+	Default     string
+	Placeholder string
+	Disabled    bool
+	Mandatory   bool
+	Tooltip     string
+	Width       int
+	X           int
+	Y           int
+	RelX        int
+	RelY        int
+	Explicit    AttributeSet
 }
 
 // PashuaPopup is a structure that holds all information for a PashuaPopup
@@ -152,6 +170,7 @@ type PashuaPopup struct {
 	Y         int
 	RelX      int
 	RelY      int
+	Explicit  AttributeSet
 }
 
 // PashuaRadioButton is a structure that holds all information for a PashuaRadioButton
@@ -166,6 +185,7 @@ type PashuaRadioButton struct {
 	Y         int
 	RelX      int
 	RelY      int
+	Explicit  AttributeSet
 }
 
 // PashuaSaveBrowser is a structure that holds all information for a PashuaSaveBrowser
@@ -176,22 +196,25 @@ type PashuaSaveBrowser struct {
 	Filetype    string
 	Placeholder string
 	Mandatory   bool
+	Tooltip     string
 	X           int
 	Y           int
 	RelX        int
 	RelY        int
+	Explicit    AttributeSet
 }
 
 // PashuaText is a structure that holds all information for a PashuaText
 type PashuaText struct {
-	Label   string
-	Text    string
-	Tooltip string
-	Width   int
-	X       int
-	Y       int
-	RelX    int
-	RelY    int
+	Label    string
+	Text     string
+	Tooltip  string
+	Width    int
+	X        int
+	Y        int
+	RelX     int
+	RelY     int
+	Explicit AttributeSet
 }
 
 // PashuaTextBox is a structure that holds all information for a PashuaTextBox
@@ -209,26 +232,41 @@ type PashuaTextBox struct {
 	Y         int
 	RelX      int
 	RelY      int
+	Explicit  AttributeSet
 }
 
 // PashuaTextField is a structure that holds all information for a PashuaTextField
 type PashuaTextField struct {
-	Label     string
-	Default   string
-	Tooltip   string
-	Mandatory bool
-	Disabled  bool
-	Width     int
-	X         int
-	Y         int
-	RelX      int
-	RelY      int
+	Label       string
+	Default     string
+	Placeholder string
+	Tooltip     string
+	Mandatory   bool
+	Disabled    bool
+	Width       int
+	X           int
+	Y           int
+	RelX        int
+	RelY        int
+	Explicit    AttributeSet
 }
 
 // PashuaComponents is type for th elist of components contained in a Pashua window
 type PashuaComponents map[string]interface{}
 
-//  PashuaWindow is the top-most structure when defininng a dialog window for Pashua
+// PashuaElement is a single component together with the key
+// that identifies it in the Pashua configuration and output
+type PashuaElement struct {
+	Key       string
+	Component interface{}
+}
+
+// PashuaElements is an ordered list of components contained in a Pashua window.
+// Pashua lays out elements without explicit coordinates in the order they
+// are defined, so unlike PashuaComponents the order is kept when encoding
+type PashuaElements []PashuaElement
+
+// PashuaWindow is the top-most structure when defininng a dialog window for Pashua
 type PashuaWindow struct {
 	AutoCloseTime int
 	AutoSaveKey   string
@@ -237,449 +275,891 @@ type PashuaWindow struct {
 	Transparency  float64
 	X             int
 	Y             int
+	Elements      PashuaElements
 	Components    PashuaComponents
+	// NoValidate skips the call to Validate in RunPashuaWithStruct
+	NoValidate bool
+	// DialogID identifies the window in an answer file, it is not sent to Pashua
+	DialogID string
+	// Explicit lists window attributes written even if zero, e.g. Attrs("x", "y")
+	Explicit AttributeSet
 }
 
-// fileExists is a helper function that returns true
-// if a specified file exists and is a file
-func fileExists(filename string) bool {
-	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
+// Add appends a component with the given key to the list
+func (elems *PashuaElements) Add(key string, comp interface{}) {
+	*elems = append(*elems, PashuaElement{Key: key, Component: comp})
+}
+
+// Get returns the component stored under key and
+// whether a component with that key exists in the list
+func (elems PashuaElements) Get(key string) (interface{}, bool) {
+	for _, elem := range elems {
+		if elem.Key == key {
+			return elem.Component, true
+		}
 	}
-	return (info != nil) && !info.IsDir()
+	return nil, false
+}
+
+// Elements converts the map of components into an ordered list.
+// As a map has no order, the components are sorted by key,
+// which gives a stable (if not necessarily pretty) layout
+func (comps PashuaComponents) Elements() PashuaElements {
+	keys := make([]string, 0, len(comps))
+	for key := range comps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make(PashuaElements, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, PashuaElement{Key: key, Component: comps[key]})
+	}
+	return result
+}
+
+func (win *PashuaWindow) explicitSet() *AttributeSet { return &win.Explicit }
+
+// allElements returns the ordered elements of the window, followed by
+// the components from the Components map (sorted by key) whose key
+// is not already used in Elements
+func (win *PashuaWindow) allElements() PashuaElements {
+	result := make(PashuaElements, 0, len(win.Elements)+len(win.Components))
+	result = append(result, win.Elements...)
+	for _, elem := range win.Components.Elements() {
+		if _, found := win.Elements.Get(elem.Key); !found {
+			result = append(result, elem)
+		}
+	}
+	return result
+}
+
+// sharedKey returns the first key (in sorted order) of the
+// Components map that is also used in Elements
+func (win *PashuaWindow) sharedKey() (string, bool) {
+	for _, elem := range win.Components.Elements() {
+		if _, found := win.Elements.Get(elem.Key); found {
+			return elem.Key, true
+		}
+	}
+	return "", false
 }
 
 // LocatePashua is one of the two main binding function
 // and tries to find the Pashua.app and the executable contained
 // within the app container by iterating over the standard
-// file locations. Returns the location path and an error code
+// file locations. Returns the location path and an error code,
+// which matches ErrPashuaNotFound if Pashua could not be found
 func LocatePashua(pashuaPath string) (string, error) {
-	const bundlePath = "Pashua.app/Contents/MacOS/Pashua"
-	usr, err := user.Current()
-	if err != nil {
-		return "", err
-	}
-	usrhome := usr.HomeDir
-	pashuaPlaces := []string{
-		path.Join(path.Dir(os.Args[0]), "Pashua"),
-		path.Join(path.Dir(os.Args[0]), bundlePath),
-		"./" + bundlePath,
-		path.Join("/Applications", bundlePath),
-		path.Join(usrhome, "Applications", bundlePath),
-		path.Join("/usr/local/bin", bundlePath),
-	}
-	if pashuaPath != "" {
-		// insert at index 0
-		// yeah, in Go there is no "insert" function,
-		// so this is a little tricky at first
-		pashuaPlaces = append(pashuaPlaces, "")
-		copy(pashuaPlaces[1:], pashuaPlaces[0:])
-		pashuaPlaces[0] = pashuaPath
-
-	}
-	for _, p := range pashuaPlaces {
-		if fileExists(p) {
-			return p, nil
-		}
-	}
-	return "", fmt.Errorf("Could not locate pashua")
+	return clientFor(pashuaPath).Locate()
 }
 
 // RunPashua is one of the two main binding function.
 // it sets up a pipe and executes Pashua as an external command,
 // then converts the STdOut and StdErr to strings and parses the output
 func RunPashua(configData string, pashuaPath string) (map[string]string, error) {
-	var err error
-	result := make(map[string]string)
-	appPath, err := LocatePashua(pashuaPath)
+	return RunPashuaContext(context.Background(), configData, pashuaPath)
+}
+
+// RunPashuaContext works like RunPashua, but kills the Pashua process
+// when ctx is cancelled or its deadline passes. In that case the returned
+// error matches ErrCancelled or ErrTimeout as well as context.Canceled
+// or context.DeadlineExceeded
+func RunPashuaContext(ctx context.Context, configData string, pashuaPath string) (map[string]string, error) {
+	return clientFor(pashuaPath).RunConfig(ctx, configData)
+}
+
+// RunPashuaWithRunner passes the configuration to the given Runner
+// and parses its output
+func RunPashuaWithRunner(ctx context.Context, runner Runner, configData string) (map[string]string, error) {
+	return NewClient(WithRunner(runner)).RunConfig(ctx, configData)
+}
+
+// RunPashuaWithStruct is a convenience function that saves you
+// from having to convert a struct-based window definition to a string first.
+// The window is validated first, unless NoValidate is set
+func RunPashuaWithStruct(pashuaWindow *PashuaWindow, pashuaPath string) (map[string]string, error) {
+	return RunPashuaWithStructContext(context.Background(), pashuaWindow, pashuaPath)
+}
+
+// RunPashuaWithStructContext is the context-aware variant of RunPashuaWithStruct
+func RunPashuaWithStructContext(ctx context.Context, pashuaWindow *PashuaWindow, pashuaPath string) (map[string]string, error) {
+	result, err := clientFor(pashuaPath).Run(ctx, pashuaWindow)
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.Command(appPath, "-")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	cmd.Stdin = bytes.NewBuffer([]byte(configData))
-	err = cmd.Run()
-	if err != nil {
-		return result, fmt.Errorf("Error: %w, pashua error text: %s", err, string(stderr.Bytes()))
+	return result.Raw(), nil
+}
+
+// returnEscape is the token Pashua uses for a newline in a value,
+// both in the configuration and in its output
+const returnEscape = "[return]"
+
+// lineBreaks replaces all kinds of line breaks with returnEscape
+var lineBreaks = strings.NewReplacer("\r\n", returnEscape, "\n", returnEscape, "\r", returnEscape)
+
+// isLineBreak returns true for the characters other than CR and LF that
+// Cocoa treats as line breaks: NEL and the Unicode line and paragraph
+// separators (U+2028, U+2029)
+func isLineBreak(r rune) bool {
+	return r == '\u0085' || unicode.In(r, unicode.Zl, unicode.Zp)
+}
+
+// isPrintableASCII returns true if value only holds printable ASCII
+// characters, which never need escaping
+func isPrintableASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < 0x20 || value[i] >= 0x7f {
+			return false
+		}
 	}
-	outStr := string(stdout.Bytes())
-	result = parsePashuaOutput(outStr)
-	return result, err
+	return true
 }
 
-// RunPashuaWithStruct is a convenience function that saves you
-// from having to convert a struct-based window definition to a string first
-func RunPashuaWithStruct(pashuaWindow *PashuaWindow, pashuaPath string) (map[string]string, error) {
-	configString := pashuaWindow.ToString()
-	return RunPashua(configString, pashuaPath)
+// needsEscape returns true for characters that escapeValue replaces
+func needsEscape(r rune) bool {
+	if r >= 0x20 && r < 0x7f {
+		// printable ASCII, the common case
+		return false
+	}
+	return unicode.IsControl(r) || isLineBreak(r)
+}
+
+// escapeValue is the single escaping layer for all values written to
+// a Pashua configuration. Line breaks (LF, CRLF, CR, NEL, U+2028 and
+// U+2029) are encoded as "[return]" and every other control character is
+// replaced by a space, so a value can never end its config line and
+// start a new one
+func escapeValue(value string) string {
+	if isPrintableASCII(value) || strings.IndexFunc(value, needsEscape) < 0 {
+		return value
+	}
+	value = lineBreaks.Replace(value)
+	var sb strings.Builder
+	for _, r := range value {
+		switch {
+		case isLineBreak(r):
+			sb.WriteString(returnEscape)
+		case unicode.IsControl(r):
+			sb.WriteByte(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// unescapeValue is the inverse of escapeValue
+// and converts "[return]" back to a newline
+func unescapeValue(value string) string {
+	return strings.Replace(value, returnEscape, "\n", -1)
+}
+
+// OutputError is returned by ParsePashuaOutputStrict
+// for a line of the Pashua output that is not a key=value pair
+type OutputError struct {
+	Line int
+	Text string
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("line %d: malformed pashua output %q", e.Line, e.Text)
 }
 
-// parsePashuaOutput takes a list of lines and
-// converts key=value pairs to a map and
-// skips empty lines of lines without an "="
-func parsePashuaOutput(output string) map[string]string {
+// ParsePashuaOutput converts the key=value lines Pashua writes to
+// stdout into a map. Values are decoded (e.g. "[return]" becomes a newline)
+// and keep all other whitespace. Malformed lines are skipped
+func ParsePashuaOutput(output string) map[string]string {
+	result, _ := parseOutput(output, false)
+	return result
+}
+
+// ParsePashuaOutputStrict works like ParsePashuaOutput, but returns an
+// *OutputError for the first line that is not a key=value pair
+func ParsePashuaOutputStrict(output string) (map[string]string, error) {
+	return parseOutput(output, true)
+}
+
+// parseOutput splits the output into lines (accepting both LF and CRLF),
+// skips empty lines and stores the decoded key=value pairs in a map
+func parseOutput(output string, strict bool) (map[string]string, error) {
 	result := make(map[string]string)
 	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for number, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
 		pos := strings.Index(line, "=")
-		if line != "" && pos > 0 {
-			key := line[:pos]
-			value := line[pos+1:]
-			result[key] = value
+		key := ""
+		if pos > 0 {
+			key = strings.TrimSpace(line[:pos])
 		}
+		if key == "" {
+			if strict {
+				return result, &OutputError{Line: number + 1, Text: line}
+			}
+			continue
+		}
+		result[key] = unescapeValue(line[pos+1:])
 	}
-	return result
+	return result, nil
 }
 
-// getFieldValue converts various types to a string
-// representation as Pashua needs the configuration as string
-func getFieldValue(field interface{}) string {
-	result := ""
-	switch field.(type) {
+// encodeValue converts the value of an attribute to the string Pashua
+// expects. Bools become "1" or "0", numbers of any kind are formatted in
+// decimal (floats with four decimals), time.Time uses the date format of
+// Pashua and time.Duration whole seconds. Other types are converted with
+// their MarshalText or String method, if they have one. Nil pointers
+// result in an empty value
+func encodeValue(value interface{}) (string, error) {
+	if isNilPointer(value) {
+		// checked first, MarshalText or String would be called on nil
+		return "", nil
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", nil
 	case string:
-		result = field.(string)
-	case int:
-		result = strconv.Itoa(field.(int))
-	case int8:
-		result = strconv.Itoa(field.(int))
-	case int16:
-		result = strconv.Itoa(field.(int))
-	case int32:
-		result = strconv.Itoa(field.(int))
-	case int64:
-		result = strconv.Itoa(field.(int))
-	case float32:
-		result = strconv.FormatFloat(field.(float64), 'f', 4, 32)
-	case float64:
-		result = strconv.FormatFloat(field.(float64), 'f', 4, 64)
+		return v, nil
 	case bool:
-		if field.(bool) {
-			return "1"
-		} else {
-			return "0"
+		return formatBool(v), nil
+	case time.Time:
+		return v.Format(dateLayout), nil
+	case time.Duration:
+		return strconv.FormatInt(int64(v.Round(time.Second)/time.Second), 10), nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return formatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', 4, rv.Type().Bits()), nil
+	case reflect.Ptr:
+		return encodeValue(rv.Elem().Interface())
+	}
+	return "", fmt.Errorf("unsupported value of type %T", value)
+}
+
+// formatBool returns the "1" or "0" Pashua uses for bools
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// PashuaAttribute is a single attribute of a component,
+// such as the "label" of a textfield
+type PashuaAttribute struct {
+	Name  string
+	Value interface{}
+}
+
+// AttributeSet is a set of attribute names. The element structs and the
+// window leave out attributes with a zero value, so that Pashua applies its
+// own defaults and layout. Attributes in their Explicit set are written even
+// if zero, e.g. Explicit: Attrs("x", "y") places an element at the origin
+type AttributeSet map[string]bool
+
+// Attrs returns a set containing the given attribute names
+func Attrs(names ...string) AttributeSet {
+	set := make(AttributeSet, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+// Has returns true if the set contains the attribute name
+func (set AttributeSet) Has(name string) bool {
+	return set[name]
+}
+
+// Component is the interface implemented by every element of a Pashua window.
+// PashuaType returns the element type Pashua expects (e.g. "textfield")
+// and PashuaAttributes the attributes in the order they are written.
+// Implement it to use elements that this package does not provide yet
+type Component interface {
+	PashuaType() string
+	PashuaAttributes() []PashuaAttribute
+}
+
+// versionTarget restricts the attributes written to those supported
+// by a Pashua version. Unsupported attributes are skipped, or result
+// in an error if strict is set
+type versionTarget struct {
+	version Version
+	strict  bool
+}
+
+// encodeComponent converts a component into the config lines
+// Pashua expects, each line prefixed with the key of the element
+func encodeComponent(key string, comp Component, target *versionTarget) (string, error) {
+	elem, err := documentElement(key, comp, target)
+	if err != nil {
+		return "", err
+	}
+	buf, err := appendElement(nil, elem)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// componentToString works like encodeComponent,
+// but returns an empty string for an invalid component
+func componentToString(key string, comp Component) string {
+	result, err := encodeComponent(key, comp, nil)
+	if err != nil {
+		return ""
+	}
+	return result
+}
+
+// attributeField is an attribute of a built-in element
+// and a pointer to the struct field holding its value
+type attributeField struct {
+	name  string
+	field interface{}
+}
+
+// fieldTable is implemented by pointers to the built-in elements. The
+// table lists each attribute once, in the order it is written, and is
+// used for encoding, ParseConfig and WindowFromStruct alike
+type fieldTable interface {
+	attributeFields() []attributeField
+	explicitSet() *AttributeSet
+}
+
+// fixedFont is used to map the textbox attribute "fonttype=fixed"
+// to the FixedFont flag of a PashuaTextBox
+type fixedFont bool
+
+// alwaysBool is a bool attribute that is written even if false,
+// because the default of Pashua is true, e.g. "date" of a date element
+type alwaysBool bool
+
+// value returns the value of the attribute
+func (f attributeField) value() interface{} {
+	switch v := f.field.(type) {
+	case *fixedFont:
+		if *v {
+			return "fixed"
+		}
+		return ""
+	case *alwaysBool:
+		return bool(*v)
+	}
+	return reflect.ValueOf(f.field).Elem().Interface()
+}
+
+// text returns the value of the attribute as written to Pashua
+func (f attributeField) text() (string, error) {
+	switch v := f.field.(type) {
+	case *string:
+		return *v, nil
+	case *bool:
+		return formatBool(*v), nil
+	case *int:
+		return strconv.Itoa(*v), nil
+	case *CompletionMode:
+		return string(*v), nil
+	case *FontSize:
+		return string(*v), nil
+	case *alwaysBool:
+		return formatBool(bool(*v)), nil
+	}
+	return encodeValue(f.value())
+}
+
+// written returns true if the attribute is written: if it is not
+// zero or listed in explicit. Options are written one by one
+func (f attributeField) written(explicit AttributeSet) bool {
+	if _, ok := f.field.(*alwaysBool); ok {
+		return true
+	}
+	return explicit.Has(f.name) || !reflect.ValueOf(f.field).Elem().IsZero()
+}
+
+// builtinPkgPath is the import path of this package
+var builtinPkgPath = reflect.TypeOf(PashuaWindow{}).PkgPath()
+
+// builtinFields returns the attribute table of comp if it is one of the
+// element structs of this package, stored as value or pointer, and nil
+// otherwise. Types embedding an element struct are not matched, as they
+// may have attributes of their own
+func builtinFields(comp Component) fieldTable {
+	rv := reflect.ValueOf(comp)
+	typ := rv.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.PkgPath() != builtinPkgPath {
+		return nil
+	}
+	if rv.Kind() != reflect.Ptr {
+		ptr := reflect.New(typ)
+		ptr.Elem().Set(rv)
+		rv = ptr
+	}
+	t, _ := rv.Interface().(fieldTable)
+	return t
+}
+
+// tableAttributes returns the attributes of a built-in element from
+// its table: one "option" attribute per option and all other
+// attributes unless they are zero and not in the Explicit set
+func tableAttributes(t fieldTable) []PashuaAttribute {
+	explicit := *t.explicitSet()
+	result := []PashuaAttribute{}
+	for _, f := range t.attributeFields() {
+		if list, ok := f.field.(*[]string); ok {
+			for _, option := range *list {
+				result = append(result, PashuaAttribute{f.name, option})
+			}
+			continue
+		}
+		if f.written(explicit) {
+			result = append(result, PashuaAttribute{f.name, f.value()})
 		}
-	case CompletionMode:
-		result = string(field.(CompletionMode))
-	case FontSize:
-		result = string(field.(FontSize))
-	default:
-		result = field.(string)
 	}
 	return result
 }
 
 /*
-the function below convert each possible component type
-into a config string that Pashua con use.
+the functions below describe each possible component type
+as a Pashua type and a table of its attributes, zero values are left
+out unless the attribute is in the Explicit set.
 the "ToString()" of the PashuaWindow iterates over all
-defines component and combines them into a large config string
+defined components and combines them into a large config string
 that can be provided to Pashua
 */
 
-func (btn *PashuaButton) ToString(key string) string {
-	result := []string{key + ".type=button"}
-	result = append(result, key+".label="+getFieldValue(btn.Label))
-	result = append(result, key+".tooltip="+getFieldValue(btn.Tooltip))
-	result = append(result, key+".disabled="+getFieldValue(btn.Disabled))
-	result = append(result, key+".x="+getFieldValue(btn.X))
-	result = append(result, key+".y="+getFieldValue(btn.Y))
-	return strings.Join(result, "\n")
+func (btn PashuaButton) PashuaType() string { return "button" }
+
+func (btn PashuaButton) PashuaAttributes() []PashuaAttribute { return tableAttributes(&btn) }
+
+func (btn *PashuaButton) explicitSet() *AttributeSet { return &btn.Explicit }
+
+func (btn *PashuaButton) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &btn.Label},
+		{"tooltip", &btn.Tooltip},
+		{"disabled", &btn.Disabled},
+		{"x", &btn.X},
+		{"y", &btn.Y},
+		{"relx", &btn.RelX},
+		{"rely", &btn.RelY},
+	}
 }
 
-func (btn *PashuaDate) ToString(key string) string {
-	result := []string{key + ".type=date"}
-	result = append(result, key+".label="+getFieldValue(btn.Label))
-	result = append(result, key+".tooltip="+getFieldValue(btn.Tooltip))
-	result = append(result, key+".disabled="+getFieldValue(btn.Disabled))
-	result = append(result, key+".default="+getFieldValue(btn.Default))
-	result = append(result, key+".date="+getFieldValue(btn.UseDate))
-	result = append(result, key+".time="+getFieldValue(btn.UseTime))
-	result = append(result, key+".textual="+getFieldValue(btn.Textual))
-	result = append(result, key+".x="+getFieldValue(btn.X))
-	result = append(result, key+".y="+getFieldValue(btn.Y))
-	return strings.Join(result, "\n")
+func (btn PashuaDate) PashuaType() string { return "date" }
+
+func (btn PashuaDate) PashuaAttributes() []PashuaAttribute { return tableAttributes(&btn) }
+
+func (btn *PashuaDate) explicitSet() *AttributeSet { return &btn.Explicit }
+
+func (btn *PashuaDate) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &btn.Label},
+		{"tooltip", &btn.Tooltip},
+		{"disabled", &btn.Disabled},
+		{"default", &btn.Default},
+		{"date", (*alwaysBool)(&btn.UseDate)},
+		{"time", &btn.UseTime},
+		{"textual", &btn.Textual},
+		{"x", &btn.X},
+		{"y", &btn.Y},
+		{"relx", &btn.RelX},
+		{"rely", &btn.RelY},
+	}
 }
 
-func (btn *PashuaDefaultButton) ToString(key string) string {
-	result := []string{key + ".type=defaultbutton"}
-	result = append(result, key+".label="+getFieldValue(btn.Label))
-	result = append(result, key+".tooltip="+getFieldValue(btn.Tooltip))
-	result = append(result, key+".disabled="+getFieldValue(btn.Disabled))
-	return strings.Join(result, "\n")
+func (btn PashuaDefaultButton) PashuaType() string { return "defaultbutton" }
+
+func (btn PashuaDefaultButton) PashuaAttributes() []PashuaAttribute { return tableAttributes(&btn) }
+
+func (btn *PashuaDefaultButton) explicitSet() *AttributeSet { return &btn.Explicit }
+
+func (btn *PashuaDefaultButton) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &btn.Label},
+		{"tooltip", &btn.Tooltip},
+		{"disabled", &btn.Disabled},
+	}
 }
 
-func (txt *PashuaCancelButton) ToString(key string) string {
-	result := []string{key + ".type=cancelbutton"}
-	result = append(result, key+".label="+getFieldValue(txt.Label))
-	result = append(result, key+".tooltip="+getFieldValue(txt.Tooltip))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	return strings.Join(result, "\n")
+func (txt PashuaCancelButton) PashuaType() string { return "cancelbutton" }
+
+func (txt PashuaCancelButton) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaCancelButton) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaCancelButton) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"tooltip", &txt.Tooltip},
+		{"disabled", &txt.Disabled},
+	}
 }
 
-func (txt *PashuaCheckbox) ToString(key string) string {
-	result := []string{key + ".type=checkbox"}
-	result = append(result, key+".label="+getFieldValue(txt.Label))
-	result = append(result, key+".default="+getFieldValue(txt.Default))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".tooltip="+getFieldValue(txt.Tooltip))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaCheckbox) PashuaType() string { return "checkbox" }
+
+func (txt PashuaCheckbox) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaCheckbox) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaCheckbox) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.Default},
+		{"disabled", &txt.Disabled},
+		{"tooltip", &txt.Tooltip},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaCombobox) ToString(key string) string {
-	result := []string{key + ".type=combobox"}
-	result = append(result, key+".label="+getFieldValue(txt.Label))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".tooltip="+getFieldValue(txt.Tooltip))
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".rows="+getFieldValue(txt.Rows))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	result = append(result, key+".placeholder="+getFieldValue(txt.Placeholder))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".completion="+getFieldValue(txt.CompletionMode))
-	for _, k := range txt.Option {
-		result = append(result, key+".option="+getFieldValue(k))
+func (txt PashuaCombobox) PashuaType() string { return "combobox" }
+
+func (txt PashuaCombobox) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaCombobox) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaCombobox) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.Default},
+		{"disabled", &txt.Disabled},
+		{"tooltip", &txt.Tooltip},
+		{"width", &txt.Width},
+		{"rows", &txt.Rows},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+		{"placeholder", &txt.Placeholder},
+		{"mandatory", &txt.Mandatory},
+		{"completion", &txt.CompletionMode},
+		{"option", &txt.Option},
 	}
-	return strings.Join(result, "\n")
 }
 
-func (txt *PashuaImage) ToString(key string) string {
-	result := []string{key + ".type=image"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".path="+txt.Path)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".height="+getFieldValue(txt.Height))
-	result = append(result, key+".maxwidth="+getFieldValue(txt.MaxWidth))
-	result = append(result, key+".maxheight="+getFieldValue(txt.MaxHeight))
-	result = append(result, key+".upscale="+getFieldValue(txt.UpScale))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaImage) PashuaType() string { return "image" }
+
+func (txt PashuaImage) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaImage) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaImage) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"path", &txt.Path},
+		{"border", &txt.Border},
+		{"tooltip", &txt.Tooltip},
+		{"width", &txt.Width},
+		{"height", &txt.Height},
+		{"maxwidth", &txt.MaxWidth},
+		{"maxheight", &txt.MaxHeight},
+		{"upscale", &txt.UpScale},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaOpenBrowser) ToString(key string) string {
-	result := []string{key + ".type=openbrowser"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".default="+txt.DefaultPath)
-	result = append(result, key+".filetype="+txt.Filetype)
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".placeholder="+getFieldValue(txt.Placeholder))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaOpenBrowser) PashuaType() string { return "openbrowser" }
+
+func (txt PashuaOpenBrowser) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaOpenBrowser) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaOpenBrowser) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.DefaultPath},
+		{"filetype", &txt.Filetype},
+		{"width", &txt.Width},
+		{"mandatory", &txt.Mandatory},
+		{"placeholder", &txt.Placeholder},
+		{"tooltip", &txt.Tooltip},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaSaveBrowser) ToString(key string) string {
-	result := []string{key + ".type=savebrowser"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".default="+txt.DefaultPath)
-	result = append(result, key+".filetype="+txt.Filetype)
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".placeholder="+getFieldValue(txt.Placeholder))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaSaveBrowser) PashuaType() string { return "savebrowser" }
+
+func (txt PashuaSaveBrowser) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaSaveBrowser) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaSaveBrowser) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.DefaultPath},
+		{"filetype", &txt.Filetype},
+		{"width", &txt.Width},
+		{"mandatory", &txt.Mandatory},
+		{"placeholder", &txt.Placeholder},
+		{"tooltip", &txt.Tooltip},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaPassword) ToString(key string) string {
-	result := []string{key + ".type=password"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".default="+getFieldValue(txt.Default))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaPassword) PashuaType() string { return "password" }
+
+func (txt PashuaPassword) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaPassword) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaPassword) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"tooltip", &txt.Tooltip},
+		{"width", &txt.Width},
+		{"default", &txt.Default},
+		{"placeholder", &txt.Placeholder},
+		{"disabled", &txt.Disabled},
+		{"mandatory", &txt.Mandatory},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaPopup) ToString(key string) string {
-	result := []string{key + ".type=popup"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".width="+getFieldValue(txt.Width))
-	result = append(result, key+".default="+getFieldValue(txt.Default))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	for _, k := range txt.Option {
-		result = append(result, key+".option="+getFieldValue(k))
+func (txt PashuaPopup) PashuaType() string { return "popup" }
+
+func (txt PashuaPopup) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaPopup) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaPopup) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"tooltip", &txt.Tooltip},
+		{"width", &txt.Width},
+		{"default", &txt.Default},
+		{"disabled", &txt.Disabled},
+		{"mandatory", &txt.Mandatory},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+		{"option", &txt.Option},
 	}
-	return strings.Join(result, "\n")
 }
 
-func (txt *PashuaRadioButton) ToString(key string) string {
-	result := []string{key + ".type=radiobutton"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".default="+getFieldValue(txt.Default))
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	for _, k := range txt.Option {
-		result = append(result, key+".option="+getFieldValue(k))
+func (txt PashuaRadioButton) PashuaType() string { return "radiobutton" }
+
+func (txt PashuaRadioButton) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaRadioButton) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaRadioButton) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"tooltip", &txt.Tooltip},
+		{"default", &txt.Default},
+		{"disabled", &txt.Disabled},
+		{"mandatory", &txt.Mandatory},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+		{"option", &txt.Option},
 	}
-	return strings.Join(result, "\n")
 }
 
-func (txt *PashuaText) ToString(key string) string {
-	result := []string{key + ".type=text"}
-	result = append(result, key+".label="+txt.Label)
-	s := getFieldValue(txt.Text)
-	s = strings.Replace(s, "\n", "[return]", -1)
-	result = append(result, key+".text="+s)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaText) PashuaType() string { return "text" }
+
+func (txt PashuaText) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaText) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaText) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"text", &txt.Text},
+		{"tooltip", &txt.Tooltip},
+		{"width", &txt.Width},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaTextBox) ToString(key string) string {
-	result := []string{key + ".type=textbox"}
-	result = append(result, key+".label="+txt.Label)
-	s := getFieldValue(txt.Default)
-	s = strings.Replace(s, "\n", "[return]", -1)
-	result = append(result, key+".default="+s)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	s = getFieldValue(txt.FixedFont)
-	if s == "1" {
-		s = "fixed"
-	} else {
-		s = ""
-	}
-	result = append(result, key+".fonttype="+s)
-	result = append(result, key+".fontsize="+getFieldValue(txt.FontSize))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaTextBox) PashuaType() string { return "textbox" }
+
+func (txt PashuaTextBox) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaTextBox) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaTextBox) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.Default},
+		{"tooltip", &txt.Tooltip},
+		{"disabled", &txt.Disabled},
+		{"mandatory", &txt.Mandatory},
+		{"fonttype", (*fixedFont)(&txt.FixedFont)},
+		{"fontsize", &txt.FontSize},
+		{"width", &txt.Width},
+		{"height", &txt.Height},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
-func (txt *PashuaTextField) ToString(key string) string {
-	result := []string{key + ".type=textfield"}
-	result = append(result, key+".label="+txt.Label)
-	result = append(result, key+".default="+txt.Default)
-	result = append(result, key+".tooltip="+txt.Tooltip)
-	result = append(result, key+".disabled="+getFieldValue(txt.Disabled))
-	result = append(result, key+".mandatory="+getFieldValue(txt.Mandatory))
-	result = append(result, key+".x="+getFieldValue(txt.X))
-	result = append(result, key+".y="+getFieldValue(txt.Y))
-	result = append(result, key+".relx="+getFieldValue(txt.RelX))
-	result = append(result, key+".rely="+getFieldValue(txt.RelY))
-	return strings.Join(result, "\n")
+func (txt PashuaTextField) PashuaType() string { return "textfield" }
+
+func (txt PashuaTextField) PashuaAttributes() []PashuaAttribute { return tableAttributes(&txt) }
+
+func (txt *PashuaTextField) explicitSet() *AttributeSet { return &txt.Explicit }
+
+func (txt *PashuaTextField) attributeFields() []attributeField {
+	return []attributeField{
+		{"label", &txt.Label},
+		{"default", &txt.Default},
+		{"placeholder", &txt.Placeholder},
+		{"tooltip", &txt.Tooltip},
+		{"disabled", &txt.Disabled},
+		{"mandatory", &txt.Mandatory},
+		{"width", &txt.Width},
+		{"x", &txt.X},
+		{"y", &txt.Y},
+		{"relx", &txt.RelX},
+		{"rely", &txt.RelY},
+	}
 }
 
+// the ToString methods of the components are kept for compatibility,
+// they return the config lines for the component using the given key
+
+func (btn *PashuaButton) ToString(key string) string { return componentToString(key, btn) }
+
+func (btn *PashuaDate) ToString(key string) string { return componentToString(key, btn) }
+
+func (btn *PashuaDefaultButton) ToString(key string) string { return componentToString(key, btn) }
+
+func (txt *PashuaCancelButton) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaCheckbox) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaCombobox) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaImage) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaOpenBrowser) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaSaveBrowser) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaPassword) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaPopup) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaRadioButton) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaText) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaTextBox) ToString(key string) string { return componentToString(key, txt) }
+
+func (txt *PashuaTextField) ToString(key string) string { return componentToString(key, txt) }
+
 func (win *PashuaWindow) WindowToString() string {
 	result := []string{}
-	autoSaveKey := getFieldValue(win.AutoSaveKey)
-	title := getFieldValue(win.Title)
-	if title != "" {
-		result = append(result, "*.title="+title)
-	}
-	if win.Transparency > 0 {
-		// do not display invisible dialogs ;-)
-		result = append(result, "*.transparency="+getFieldValue(win.Transparency))
-	}
-	if win.AutoCloseTime > 1 {
-		result = append(result, "*.autoclosetime="+getFieldValue(win.AutoCloseTime))
-	}
-	if autoSaveKey != "" {
-		result = append(result, "*.autosavekey="+autoSaveKey)
-	} else {
-		result = append(result, "*.x="+getFieldValue(win.X))
-		result = append(result, "*.y="+getFieldValue(win.Y))
-	}
-	if win.Floating {
-		result = append(result, "*.floating=1")
+	for _, attr := range windowAttributes(win) {
+		result = append(result, "*."+attr.Name+"="+escapeValue(attr.Value))
 	}
 	return strings.Join(result, "\n")
 }
 
+// Encode converts the window and all of its components into
+// the configuration string Pashua expects. Components can be stored as
+// values or pointers, anything that does not implement Component
+// results in an error, as does a key used in both Components and Elements
+func (win *PashuaWindow) Encode() (string, error) {
+	return win.encode(false, nil)
+}
+
+// EncodeForVersion works like Encode, but leaves out attributes that
+// the given Pashua version does not support. If strict is set, such
+// attributes result in an *UnsupportedAttributeError instead
+func (win *PashuaWindow) EncodeForVersion(v Version, strict bool) (string, error) {
+	return win.encode(false, &versionTarget{version: v, strict: strict})
+}
+
+// ToString converts the window into a configuration string.
+// Components that do not implement Component and entries of the
+// Components map whose key is used in Elements are skipped,
+// use Encode to get an error for them instead
 func (win *PashuaWindow) ToString() string {
-	var result = []string{}
-	result = append(result, win.WindowToString())
-	for key, comp := range win.Components {
-		switch comp.(type) {
-		case PashuaButton:
-			btn := comp.(PashuaButton)
-			result = append(result, btn.ToString(key))
-		case PashuaCancelButton:
-			txt := comp.(PashuaCancelButton)
-			result = append(result, txt.ToString(key))
-		case PashuaCheckbox:
-			txt := comp.(PashuaCheckbox)
-			result = append(result, txt.ToString(key))
-		case PashuaCombobox:
-			txt := comp.(PashuaCombobox)
-			result = append(result, txt.ToString(key))
-		case PashuaDate:
-			txt := comp.(PashuaDate)
-			result = append(result, txt.ToString(key))
-		case PashuaDefaultButton:
-			txt := comp.(PashuaDefaultButton)
-			result = append(result, txt.ToString(key))
-		case PashuaImage:
-			txt := comp.(PashuaImage)
-			result = append(result, txt.ToString(key))
-		case PashuaOpenBrowser:
-			txt := comp.(PashuaOpenBrowser)
-			result = append(result, txt.ToString(key))
-		case PashuaPassword:
-			txt := comp.(PashuaPassword)
-			result = append(result, txt.ToString(key))
-		case PashuaPopup:
-			txt := comp.(PashuaPopup)
-			result = append(result, txt.ToString(key))
-		case PashuaRadioButton:
-			txt := comp.(PashuaRadioButton)
-			result = append(result, txt.ToString(key))
-		case PashuaSaveBrowser:
-			txt := comp.(PashuaSaveBrowser)
-			result = append(result, txt.ToString(key))
-		case PashuaText:
-			txt := comp.(PashuaText)
-			result = append(result, txt.ToString(key))
-		case PashuaTextBox:
-			txt := comp.(PashuaTextBox)
-			result = append(result, txt.ToString(key))
-		case PashuaTextField:
-			txt := comp.(PashuaTextField)
-			result = append(result, txt.ToString(key))
-		}
+	var sb strings.Builder
+	win.writeConfig(&sb, true, nil)
+	return sb.String()
+}
+
+// WriteTo writes the configuration of the window to w, like Encode but
+// without building the whole configuration in memory. If a component
+// cannot be encoded, the error is returned after the preceding
+// components have been written
+func (win *PashuaWindow) WriteTo(w io.Writer) (int64, error) {
+	return win.writeConfig(w, false, nil)
+}
+
+// encode returns the configuration written by writeConfig as string
+func (win *PashuaWindow) encode(skipUnknown bool, target *versionTarget) (string, error) {
+	var sb strings.Builder
+	if _, err := win.writeConfig(&sb, skipUnknown, target); err != nil {
+		return "", err
 	}
-	return strings.Join(result, "\n")
+	return sb.String(), nil
 }
 
+// writeConfig writes the window attributes and the lines of all
+// components to w. Each component is converted into a document element
+// and written to a buffer that is reused, so that a failing component
+// can be skipped if skipUnknown is set and large windows need only a
+// few allocations
+func (win *PashuaWindow) writeConfig(w io.Writer, skipUnknown bool, target *versionTarget) (int64, error) {
+	cw := &configWriter{w: w, buf: make([]byte, 0, flushSize)}
+	if err := cw.window(windowAttributes(win)); err != nil {
+		return cw.written, err
+	}
+	err := win.documentElements(skipUnknown, target, func(elem DocumentElement) error {
+		if err := cw.element(elem); err != nil && !skipUnknown {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return cw.written, err
+	}
+	return cw.written, cw.flush()
+}
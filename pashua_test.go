@@ -0,0 +1,350 @@
+package pashua
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// elementTypes are the Pashua types of all built-in elements
+var elementTypes = []string{
+	"button", "cancelbutton", "checkbox", "combobox", "date", "defaultbutton", "image",
+	"openbrowser", "password", "popup", "radiobutton", "savebrowser", "text", "textbox", "textfield",
+}
+
+func TestEscapeValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"", ""},
+		{"a\nb", "a[return]b"},
+		{"a\r\nb", "a[return]b"},
+		{"a\rb", "a[return]b"},
+		{"a\u0085b", "a[return]b"},
+		{"a\u2028b", "a[return]b"},
+		{"a\u2029b", "a[return]b"},
+		{"a\tb\x00c\x1b", "a b c "},
+		{"  spaces kept  ", "  spaces kept  "},
+		{"Grüße ✓", "Grüße ✓"},
+	}
+	for _, test := range tests {
+		if got := escapeValue(test.value); got != test.want {
+			t.Errorf("escapeValue(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+// hostile values try to end the current config line and add new ones
+var hostile = []string{
+	"x\n*.title=pwned",
+	"x\r\nevil.type=defaultbutton",
+	"x\revil.type=text",
+	"x\u2028evil.type=text\u2029*.title=pwned",
+	"x\u0085evil.label=y",
+	"x\x00\x0b\x0c*.floating=1",
+}
+
+// fillFields sets every field of the struct v points to, except Explicit:
+// strings to value, string lists to two copies of it, bools to true
+// and ints to 7
+func fillFields(v interface{}, value string) {
+	rv := reflect.ValueOf(v).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int:
+			field.SetInt(7)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.String {
+				field.Set(reflect.ValueOf([]string{value, value}))
+			}
+		}
+	}
+}
+
+func TestHostileInputCannotAddElements(t *testing.T) {
+	for _, typ := range elementTypes {
+		for _, value := range hostile {
+			comp := newComponent(typ)
+			fillFields(comp, value)
+			win := &PashuaWindow{Title: "safe", AutoSaveKey: value}
+			win.Elements.Add("elem", comp)
+			config, err := win.Encode()
+			if err != nil {
+				t.Fatalf("%s: %v", typ, err)
+			}
+			parsed, err := ParseDocument(strings.NewReader(config))
+			if err != nil {
+				t.Fatalf("%s with %q: config does not parse: %v\n%s", typ, value, err, config)
+			}
+			if len(parsed.Elements) != 1 || parsed.Elements[0].Key != "elem" || parsed.Elements[0].Type != typ {
+				t.Errorf("%s with %q: elements changed:\n%s", typ, value, config)
+			}
+			if len(parsed.Window) != 2 || parsed.Window[0].Name != "title" || parsed.Window[0].Value != "safe" ||
+				parsed.Window[1].Name != "autosavekey" {
+				t.Errorf("%s with %q: window attributes changed:\n%s", typ, value, config)
+			}
+		}
+	}
+}
+
+func TestHostileInputRoundTrip(t *testing.T) {
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{Label: "a\nb", Default: "line 1\nline 2"})
+	config, err := win.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, _ := parsed.Elements.Get("tf")
+	if got := tf.(PashuaTextField).Default; got != "line 1\nline 2" {
+		t.Errorf("default = %q, want the original lines", got)
+	}
+}
+
+func TestInvalidNamesAreRejected(t *testing.T) {
+	for _, key := range []string{"", "a.b", "a=b", "a\nb", "*"} {
+		win := &PashuaWindow{}
+		win.Elements.Add(key, PashuaButton{Label: "x"})
+		if _, err := win.Encode(); err == nil {
+			t.Errorf("key %q: expected an error", key)
+		}
+	}
+}
+
+func TestParsePashuaOutput(t *testing.T) {
+	output := "tb=first[return]second\r\ntf=  padded  \r\nempty=\neq=a=b\n\nnoequals\n"
+	got := ParsePashuaOutput(output)
+	want := map[string]string{"tb": "first\nsecond", "tf": "  padded  ", "empty": "", "eq": "a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePashuaOutput = %q, want %q", got, want)
+	}
+	_, err := ParsePashuaOutputStrict(output)
+	var outErr *OutputError
+	if !errors.As(err, &outErr) || outErr.Line != 6 || outErr.Text != "noequals" {
+		t.Errorf("ParsePashuaOutputStrict error = %v, want line 6", err)
+	}
+}
+
+func TestOutputIsInverseOfEncoding(t *testing.T) {
+	values := map[string]string{"a": "line 1\nline 2", "b": " x ", "c": ""}
+	got, err := ParsePashuaOutputStrict(FormatPashuaOutput(values))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, values) {
+		t.Errorf("got %q, want %q", got, values)
+	}
+}
+
+func TestDateAlwaysWritesDate(t *testing.T) {
+	tests := []struct {
+		date PashuaDate
+		want string
+	}{
+		{PashuaDate{Label: "When"}, "d.type=date\nd.label=When\nd.date=0"},
+		{PashuaDate{UseTime: true}, "d.type=date\nd.date=0\nd.time=1"},
+		{PashuaDate{UseDate: true, UseTime: true}, "d.type=date\nd.date=1\nd.time=1"},
+	}
+	for _, test := range tests {
+		win := &PashuaWindow{}
+		win.Elements.Add("d", test.date)
+		if got, err := win.Encode(); err != nil || got != test.want {
+			t.Errorf("%+v: %q, %v, want %q", test.date, got, err, test.want)
+		}
+	}
+}
+
+// level has a String method on a pointer receiver
+type level int
+
+func (l *level) String() string { return [...]string{"low", "high"}[*l] }
+
+func TestEncodeValue(t *testing.T) {
+	var nilTime *time.Time
+	var nilLevel *level
+	var nilIP net.IP
+	high := level(1)
+	width := 300
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{true, "1"},
+		{false, "0"},
+		{int8(-3), "-3"},
+		{uint(7), "7"},
+		{0.5, "0.5000"},
+		{float32(1.25), "1.2500"},
+		{time.Date(2024, 2, 29, 13, 5, 0, 0, time.UTC), "2024-02-29 13:05"},
+		{90*time.Second + 400*time.Millisecond, "90"},
+		{net.ParseIP("10.0.0.1"), "10.0.0.1"},
+		{&high, "high"},
+		{&width, "300"},
+		{nilTime, ""},
+		{nilLevel, ""},
+		{nilIP, ""},
+	}
+	for _, test := range tests {
+		got, err := encodeValue(test.value)
+		if err != nil || got != test.want {
+			t.Errorf("encodeValue(%#v) = %q, %v, want %q", test.value, got, err, test.want)
+		}
+	}
+	if _, err := encodeValue(struct{}{}); err == nil {
+		t.Error("encodeValue(struct{}{}): expected an error")
+	}
+}
+
+func TestElementLines(t *testing.T) {
+	tests := []struct {
+		comp Component
+		want []string
+	}{
+		{PashuaButton{}, nil},
+		{
+			PashuaButton{Label: "Help", Tooltip: "tip", Disabled: true, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Help", "tooltip=tip", "disabled=1", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{PashuaCancelButton{Label: "Cancel", Tooltip: "tip", Disabled: true}, []string{"label=Cancel", "tooltip=tip", "disabled=1"}},
+		{PashuaDefaultButton{Label: "OK", Tooltip: "tip", Disabled: true}, []string{"label=OK", "tooltip=tip", "disabled=1"}},
+		{
+			PashuaCheckbox{Label: "Debug", Default: true, Disabled: true, Tooltip: "tip", X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Debug", "default=1", "disabled=1", "tooltip=tip", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaCombobox{
+				Label: "City", Option: []string{"Bonn", "Köln"}, Default: "Köln", CompletionMode: CaseInsensitive,
+				Mandatory: true, Rows: 5, Placeholder: "type", Disabled: true, Tooltip: "tip", Width: 200, X: 1, Y: 2, RelX: 3, RelY: 4,
+			},
+			[]string{
+				"label=City", "default=Köln", "disabled=1", "tooltip=tip", "width=200", "rows=5", "x=1", "y=2", "relx=3", "rely=4",
+				"placeholder=type", "mandatory=1", "completion=2", "option=Bonn", "option=Köln",
+			},
+		},
+		{PashuaDate{}, []string{"date=0"}},
+		{
+			PashuaDate{Label: "When", Textual: true, UseDate: true, UseTime: true, Default: "2024-02-29 13:05", Disabled: true, Tooltip: "tip", X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=When", "tooltip=tip", "disabled=1", "default=2024-02-29 13:05", "date=1", "time=1", "textual=1", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaImage{Label: "Logo", Path: "/logo.png", Border: true, Width: 10, Height: 20, MaxWidth: 30, MaxHeight: 40, UpScale: true, Tooltip: "tip", X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Logo", "path=/logo.png", "border=1", "tooltip=tip", "width=10", "height=20", "maxwidth=30", "maxheight=40", "upscale=1", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaOpenBrowser{Label: "File", DefaultPath: "/tmp", Width: 300, Filetype: "jpg png", Placeholder: "choose", Mandatory: true, Tooltip: "tip", X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=File", "default=/tmp", "filetype=jpg png", "width=300", "mandatory=1", "placeholder=choose", "tooltip=tip", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaSaveBrowser{Label: "File", DefaultPath: "/tmp/a.txt", Width: 300, Filetype: "txt", Placeholder: "choose", Mandatory: true, Tooltip: "tip", X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=File", "default=/tmp/a.txt", "filetype=txt", "width=300", "mandatory=1", "placeholder=choose", "tooltip=tip", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaPassword{Label: "Password", Default: "secret", Placeholder: "enter", Disabled: true, Mandatory: true, Tooltip: "tip", Width: 100, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Password", "tooltip=tip", "width=100", "default=secret", "placeholder=enter", "disabled=1", "mandatory=1", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaPopup{Option: []string{"small", "large"}, Default: "large", Label: "Size", Disabled: true, Tooltip: "tip", Mandatory: true, Width: 100, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Size", "tooltip=tip", "width=100", "default=large", "disabled=1", "mandatory=1", "x=1", "y=2", "relx=3", "rely=4", "option=small", "option=large"},
+		},
+		{
+			PashuaRadioButton{Option: []string{"yes", "no"}, Default: "no", Label: "Sure?", Disabled: true, Tooltip: "tip", Mandatory: true, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Sure?", "tooltip=tip", "default=no", "disabled=1", "mandatory=1", "x=1", "y=2", "relx=3", "rely=4", "option=yes", "option=no"},
+		},
+		{
+			PashuaText{Label: "Note", Text: "line 1\nline 2", Tooltip: "tip", Width: 300, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Note", "text=line 1[return]line 2", "tooltip=tip", "width=300", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaTextBox{Label: "Log", Default: "a", Tooltip: "tip", FixedFont: true, FontSize: Small, Mandatory: true, Disabled: true, Width: 300, Height: 200, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Log", "default=a", "tooltip=tip", "disabled=1", "mandatory=1", "fonttype=fixed", "fontsize=small", "width=300", "height=200", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{
+			PashuaTextField{Label: "Name", Default: "Wallace", Placeholder: "name", Tooltip: "tip", Mandatory: true, Disabled: true, Width: 200, X: 1, Y: 2, RelX: 3, RelY: 4},
+			[]string{"label=Name", "default=Wallace", "placeholder=name", "tooltip=tip", "disabled=1", "mandatory=1", "width=200", "x=1", "y=2", "relx=3", "rely=4"},
+		},
+		{PashuaTextField{Explicit: Attrs("x", "y", "default")}, []string{"default=", "x=0", "y=0"}},
+	}
+	for _, test := range tests {
+		win := &PashuaWindow{}
+		win.Elements.Add("e", test.comp)
+		got, err := win.Encode()
+		if err != nil {
+			t.Fatalf("%#v: %v", test.comp, err)
+		}
+		want := []string{"e.type=" + test.comp.PashuaType()}
+		for _, line := range test.want {
+			want = append(want, "e."+line)
+		}
+		if got != strings.Join(want, "\n") {
+			t.Errorf("%T:\ngot\n%s\nwant\n%s", test.comp, got, strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestComponentsElementsSorted(t *testing.T) {
+	comps := PashuaComponents{"b": PashuaText{}, "a_2": PashuaText{}, "C": PashuaText{}, "a": PashuaText{}}
+	var keys []string
+	for _, elem := range comps.Elements() {
+		keys = append(keys, elem.Key)
+	}
+	if want := []string{"C", "a", "a_2", "b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %q, want %q", keys, want)
+	}
+	if elems := (PashuaComponents{}).Elements(); len(elems) != 0 {
+		t.Errorf("empty map: %q", elems)
+	}
+}
+
+func TestEmissionOrder(t *testing.T) {
+	win := &PashuaWindow{Title: "Order"}
+	win.Elements.Add("z", PashuaText{Text: "first"})
+	win.Elements.Add("m", PashuaText{Text: "second"})
+	win.Components = PashuaComponents{"b": PashuaText{Text: "fourth"}, "a": PashuaText{Text: "third"}}
+	want := "*.title=Order\nz.type=text\nz.text=first\nm.type=text\nm.text=second\n" +
+		"a.type=text\na.text=third\nb.type=text\nb.text=fourth"
+	config, err := win.Encode()
+	if err != nil || config != want {
+		t.Errorf("Encode = %q, %v, want %q", config, err, want)
+	}
+	if got := win.ToString(); got != want {
+		t.Errorf("ToString = %q, want %q", got, want)
+	}
+	var sb strings.Builder
+	if _, err := win.WriteTo(&sb); err != nil || strings.TrimSuffix(sb.String(), "\n") != want {
+		t.Errorf("WriteTo = %q, %v, want %q", sb.String(), err, want)
+	}
+}
+
+func TestKeyInElementsAndComponents(t *testing.T) {
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{Label: "Elements"})
+	win.Components = PashuaComponents{"tf": PashuaTextField{Label: "Components"}, "ok": PashuaDefaultButton{}}
+	if _, err := win.Encode(); err == nil || !strings.Contains(err.Error(), `"tf"`) {
+		t.Errorf("Encode: error %v, want one naming tf", err)
+	}
+	var sb strings.Builder
+	if n, err := win.WriteTo(&sb); err == nil || n != 0 {
+		t.Errorf("WriteTo wrote %d bytes, error %v, want nothing and an error", n, err)
+	}
+	if _, err := win.Document(); err == nil {
+		t.Error("Document: no error")
+	}
+	if got, want := win.ToString(), "tf.type=textfield\ntf.label=Elements\nok.type=defaultbutton"; got != want {
+		t.Errorf("ToString = %q, want %q", got, want)
+	}
+}
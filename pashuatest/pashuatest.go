@@ -0,0 +1,248 @@
+// Package pashuatest helps testing code that shows Pashua dialogs.
+// A Runner replaces Pashua, checks every dialog against the expectations
+// registered with ExpectDialog and answers with scripted values:
+//
+//	r := pashuatest.Install(t)
+//	r.ExpectDialog("Settings").
+//		WithElement("name", pashua.PashuaTextField{Label: "Name"}).
+//		Respond(map[string]string{"name": "Wallace", "ok": "1"})
+//
+//	showSettingsDialog() // calls pashua.RunPashuaWithStruct
+//
+// Expectations are consumed in order; Install and NewRunner check at the
+// end of the test that all of them were used.
+package pashuatest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	pashua "github.com/derlinkshaender/pashua-binding-go"
+)
+
+// Expectation describes a dialog the code under test is expected to show
+// and the answer it gets
+type Expectation struct {
+	title    string
+	elements pashua.PashuaElements
+	response map[string]string
+	err      error
+}
+
+// WithElement expects the dialog to contain an element with the given key
+// whose attributes match those of comp, regardless of their order
+func (e *Expectation) WithElement(key string, comp pashua.Component) *Expectation {
+	e.elements.Add(key, comp)
+	return e
+}
+
+// Respond sets the values returned for the dialog. Without a response,
+// the defaults of the dialog are returned (see pashua.DefaultAnswers)
+func (e *Expectation) Respond(values map[string]string) *Expectation {
+	e.response = values
+	return e
+}
+
+// RespondError makes the dialog fail with err
+func (e *Expectation) RespondError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Runner is a pashua.Runner that checks each dialog against
+// the next expectation and reports mismatches to the test
+type Runner struct {
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+	next         int
+}
+
+// NewRunner creates a Runner reporting to t. Pass it to pashua.WithRunner
+// or use Install to make all package functions use it
+func NewRunner(t testing.TB) *Runner {
+	r := &Runner{t: t}
+	t.Cleanup(r.AssertExpectationsMet)
+	return r
+}
+
+// Install creates a Runner and sets it as pashua.DefaultRunner
+// until the end of the test
+func Install(t testing.TB) *Runner {
+	r := NewRunner(t)
+	previous := pashua.DefaultRunner
+	pashua.DefaultRunner = r
+	t.Cleanup(func() { pashua.DefaultRunner = previous })
+	return r
+}
+
+// ExpectDialog registers a dialog with the given window title
+func (r *Runner) ExpectDialog(title string) *Expectation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := &Expectation{title: title}
+	r.expectations = append(r.expectations, e)
+	return e
+}
+
+// errUnexpected is returned to the code under test for dialogs
+// that do not match the expectations
+var errUnexpected = errors.New("pashuatest: unexpected dialog")
+
+// Run checks the dialog against the next expectation and returns its response
+func (r *Runner) Run(ctx context.Context, config string) (string, error) {
+	r.t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	doc, err := pashua.ParseDocument(strings.NewReader(config))
+	if err != nil {
+		r.t.Errorf("pashuatest: invalid configuration: %v\n%s", err, config)
+		return "", err
+	}
+	title := ""
+	for _, attr := range doc.Window {
+		if attr.Name == "title" {
+			title = attr.Value
+		}
+	}
+	if r.next >= len(r.expectations) {
+		r.t.Errorf("pashuatest: unexpected dialog %q, no more dialogs expected", title)
+		return "", errUnexpected
+	}
+	e := r.expectations[r.next]
+	r.next++
+	if title != e.title {
+		r.t.Errorf("pashuatest: dialog %d: title %q, want %q", r.next, title, e.title)
+		return "", errUnexpected
+	}
+	failed := false
+	for _, elem := range e.elements {
+		if diff := DocumentElementDiff(doc, elem.Key, elem.Component.(pashua.Component)); diff != "" {
+			r.t.Errorf("pashuatest: dialog %q: %s", title, diff)
+			failed = true
+		}
+	}
+	if failed {
+		return "", errUnexpected
+	}
+	if e.err != nil {
+		return "", e.err
+	}
+	response := e.response
+	if response == nil {
+		response = doc.DefaultAnswers()
+	}
+	return pashua.FormatPashuaOutput(response), nil
+}
+
+// AssertExpectationsMet reports every expected dialog that was not shown
+func (r *Runner) AssertExpectationsMet() {
+	r.t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.expectations[r.next:] {
+		r.t.Errorf("pashuatest: expected dialog %q was not shown", e.title)
+	}
+	r.next = len(r.expectations)
+}
+
+// AssertElement reports an error if the window has no element with the
+// given key or if its attributes differ from those of want
+func AssertElement(t testing.TB, win *pashua.PashuaWindow, key string, want pashua.Component) {
+	t.Helper()
+	if diff := ElementDiff(win, key, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// ElementDiff compares the element with the given key to want and
+// describes the differences, it returns an empty string if they match.
+// The order of the attributes does not matter, only the order of
+// repeated attributes such as options
+func ElementDiff(win *pashua.PashuaWindow, key string, want pashua.Component) string {
+	got, found := win.Elements.Get(key)
+	if !found {
+		got, found = win.Components[key]
+	}
+	if !found {
+		return fmt.Sprintf("element %q not found", key)
+	}
+	gotComp, ok := got.(pashua.Component)
+	if !ok {
+		return fmt.Sprintf("element %q: type %T does not implement pashua.Component", key, got)
+	}
+	gotElem, err := pashua.NewDocumentElement(key, gotComp)
+	if err != nil {
+		return err.Error()
+	}
+	return elementDiff(gotElem, want)
+}
+
+// DocumentElementDiff works like ElementDiff for an element of a
+// document, e.g. a configuration read by pashua.ParseDocument. Elements
+// of any type can be compared, including custom components
+func DocumentElementDiff(doc *pashua.Document, key string, want pashua.Component) string {
+	for _, elem := range doc.Elements {
+		if elem.Key == key {
+			return elementDiff(elem, want)
+		}
+	}
+	return fmt.Sprintf("element %q not found", key)
+}
+
+// elementDiff compares a document element to want
+func elementDiff(got pashua.DocumentElement, want pashua.Component) string {
+	key := got.Key
+	wantElem, err := pashua.NewDocumentElement(key, want)
+	if err != nil {
+		return fmt.Sprintf("expected component: %v", err)
+	}
+	wantAttrs, gotAttrs := attributes(wantElem), attributes(got)
+	names := []string{}
+	for name := range wantAttrs {
+		names = append(names, name)
+	}
+	for name := range gotAttrs {
+		if _, found := wantAttrs[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diffs := []string{}
+	for _, name := range names {
+		w, g := wantAttrs[name], gotAttrs[name]
+		if strings.Join(w, "\n") != strings.Join(g, "\n") || len(w) != len(g) {
+			diffs = append(diffs, fmt.Sprintf("  %s: got %s, want %s", name, quoteAll(g), quoteAll(w)))
+		}
+	}
+	if len(diffs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("element %q differs:\n%s", key, strings.Join(diffs, "\n"))
+}
+
+// attributes returns the values of a document element
+// by attribute name, including the type
+func attributes(elem pashua.DocumentElement) map[string][]string {
+	result := map[string][]string{"type": {elem.Type}}
+	for _, attr := range elem.Attributes {
+		result[attr.Name] = append(result[attr.Name], attr.Value)
+	}
+	return result
+}
+
+// quoteAll formats the values of an attribute for a failure message
+func quoteAll(values []string) string {
+	switch len(values) {
+	case 0:
+		return "(none)"
+	case 1:
+		return fmt.Sprintf("%q", values[0])
+	}
+	return fmt.Sprintf("%q", values)
+}
@@ -0,0 +1,124 @@
+package pashuatest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	pashua "github.com/derlinkshaender/pashua-binding-go"
+)
+
+// recorder collects the failures a Runner reports
+type recorder struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+// slider is a custom component that ParseConfig does not know
+type slider struct {
+	Label string
+	Max   int
+}
+
+func (s slider) PashuaType() string { return "slider" }
+
+func (s slider) PashuaAttributes() []pashua.PashuaAttribute {
+	return []pashua.PashuaAttribute{{Name: "label", Value: s.Label}, {Name: "max", Value: s.Max}}
+}
+
+// settingsWindow is the dialog shown by the code under test
+func settingsWindow() *pashua.PashuaWindow {
+	win := &pashua.PashuaWindow{Title: "Settings"}
+	win.Elements.Add("name", pashua.PashuaTextField{Label: "Name", Default: "Wallace"})
+	win.Elements.Add("size", pashua.PashuaPopup{Label: "Size", Option: []string{"small", "large"}})
+	win.Elements.Add("level", slider{Label: "Level", Max: 10})
+	win.Elements.Add("ok", pashua.PashuaDefaultButton{})
+	return win
+}
+
+func TestRunnerMatchesDialog(t *testing.T) {
+	rec := &recorder{}
+	r := NewRunner(rec)
+	r.ExpectDialog("Settings").
+		WithElement("size", pashua.PashuaPopup{Option: []string{"small", "large"}, Label: "Size"}).
+		WithElement("level", slider{Label: "Level", Max: 10})
+	r.ExpectDialog("Settings").Respond(map[string]string{"name": "Gromit", "ok": "1"})
+
+	client := pashua.NewClient(pashua.WithRunner(r))
+	res, err := client.Run(context.Background(), settingsWindow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Raw(); got["name"] != "Wallace" || got["size"] != "small" || got["ok"] != "1" {
+		t.Errorf("default answers = %q", got)
+	}
+	res, err = client.Run(context.Background(), settingsWindow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := res.String("name"); name != "Gromit" {
+		t.Errorf("name = %q, want the response", name)
+	}
+	for _, f := range rec.cleanups {
+		f()
+	}
+	if len(rec.errors) > 0 {
+		t.Errorf("unexpected failures: %q", rec.errors)
+	}
+}
+
+func TestRunnerReportsMismatches(t *testing.T) {
+	rec := &recorder{}
+	r := NewRunner(rec)
+	r.ExpectDialog("Settings").WithElement("level", slider{Label: "Level", Max: 5})
+	r.ExpectDialog("Other")
+
+	client := pashua.NewClient(pashua.WithRunner(r))
+	if _, err := client.Run(context.Background(), settingsWindow()); err == nil {
+		t.Error("mismatching dialog did not fail")
+	}
+	for _, f := range rec.cleanups {
+		f()
+	}
+	if len(rec.errors) != 2 {
+		t.Fatalf("failures = %q, want the differing attribute and the missing dialog", rec.errors)
+	}
+	if want := `max: got "10", want "5"`; !strings.Contains(rec.errors[0], want) {
+		t.Errorf("failure %q does not contain %q", rec.errors[0], want)
+	}
+	if want := `"Other" was not shown`; !strings.Contains(rec.errors[1], want) {
+		t.Errorf("failure %q does not contain %q", rec.errors[1], want)
+	}
+}
+
+func TestElementDiff(t *testing.T) {
+	win := settingsWindow()
+	if diff := ElementDiff(win, "name", pashua.PashuaTextField{Default: "Wallace", Label: "Name"}); diff != "" {
+		t.Errorf("equal element: %s", diff)
+	}
+	diff := ElementDiff(win, "size", pashua.PashuaPopup{Label: "Size", Option: []string{"large", "small"}, Default: "large"})
+	for _, want := range []string{`default: got (none), want "large"`, `option: got ["small" "large"], want ["large" "small"]`} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff %q does not contain %q", diff, want)
+		}
+	}
+	if diff := ElementDiff(win, "missing", pashua.PashuaText{}); diff != `element "missing" not found` {
+		t.Errorf("missing element: %q", diff)
+	}
+}
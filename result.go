@@ -0,0 +1,145 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeyNotFound is returned by the accessors of Result
+// if Pashua did not return a value for the requested key
+var ErrKeyNotFound = errors.New("key not found in result")
+
+// Result holds the values Pashua returned for a window
+// and knows which of the keys belong to buttons
+type Result struct {
+	values  map[string]string
+	cancel  string
+	buttons []string
+}
+
+// NewResult wraps the values returned by Pashua for the given window
+func NewResult(win *PashuaWindow, values map[string]string) *Result {
+	result := &Result{values: values}
+	if result.values == nil {
+		result.values = make(map[string]string)
+	}
+	if win == nil {
+		return result
+	}
+	for _, elem := range win.allElements() {
+		comp, ok := elem.Component.(Component)
+		if !ok || isNilPointer(comp) {
+			continue
+		}
+		switch comp.PashuaType() {
+		case "cancelbutton":
+			result.cancel = elem.Key
+			result.buttons = append(result.buttons, elem.Key)
+		case "button", "defaultbutton":
+			result.buttons = append(result.buttons, elem.Key)
+		}
+	}
+	return result
+}
+
+// RunPashuaWithResult runs Pashua for the given window
+// and returns the values wrapped in a Result
+func RunPashuaWithResult(pashuaWindow *PashuaWindow, pashuaPath string) (*Result, error) {
+	return RunPashuaWithResultContext(context.Background(), pashuaWindow, pashuaPath)
+}
+
+// RunPashuaWithResultContext is the context-aware variant of RunPashuaWithResult
+func RunPashuaWithResultContext(ctx context.Context, pashuaWindow *PashuaWindow, pashuaPath string) (*Result, error) {
+	return clientFor(pashuaPath).Run(ctx, pashuaWindow)
+}
+
+// Raw returns the values as returned by Pashua
+func (r *Result) Raw() map[string]string {
+	return r.values
+}
+
+// String returns the value for key
+func (r *Result) String(key string) (string, error) {
+	value, found := r.values[key]
+	if !found {
+		return "", fmt.Errorf("%w: %q", ErrKeyNotFound, key)
+	}
+	return value, nil
+}
+
+// Bool returns the value for key as bool, Pashua uses "1" and "0"
+// for checkboxes and buttons
+func (r *Result) Bool(key string) (bool, error) {
+	value, err := r.String(key)
+	if err != nil {
+		return false, err
+	}
+	switch value {
+	case "1":
+		return true, nil
+	case "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool value %q for key %q", value, key)
+}
+
+// Int returns the value for key as int
+func (r *Result) Int(key string) (int, error) {
+	value, err := r.String(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid int value %q for key %q", value, key)
+	}
+	return i, nil
+}
+
+// Float returns the value for key as float64
+func (r *Result) Float(key string) (float64, error) {
+	value, err := r.String(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float value %q for key %q", value, key)
+	}
+	return f, nil
+}
+
+// Time returns the value of a date element for key as time.Time
+// in the local time zone
+func (r *Result) Time(key string) (time.Time, error) {
+	value, err := r.String(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v for key %q", err, key)
+	}
+	return t, nil
+}
+
+// Cancelled returns true if the window was closed using its cancel button
+func (r *Result) Cancelled() bool {
+	return r.cancel != "" && r.values[r.cancel] == "1"
+}
+
+// ClickedButton returns the key of the button that closed the window.
+// The second return value is false if no button was clicked,
+// for example because the window was closed after AutoCloseTime
+func (r *Result) ClickedButton() (string, bool) {
+	for _, key := range r.buttons {
+		if r.values[key] == "1" {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,123 @@
+package pashua
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// resultWindow has a field, a checkbox and all three kinds of button
+func resultWindow() *PashuaWindow {
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{})
+	win.Elements.Add("cb", PashuaCheckbox{})
+	win.Elements.Add("help", PashuaButton{Label: "Help"})
+	win.Elements.Add("cancel", PashuaCancelButton{})
+	win.Components = PashuaComponents{"ok": PashuaDefaultButton{}}
+	return win
+}
+
+func TestResultAccessors(t *testing.T) {
+	r := NewResult(resultWindow(), map[string]string{
+		"tf": "Wallace", "cb": "1", "off": "0", "empty": "", "n": " 42 ",
+		"f": "0.5", "date": "2024-02-29 13:45", "day": "2024-02-29", "bad": "x",
+	})
+	tests := []struct {
+		key  string
+		get  func(r *Result, key string) (interface{}, error)
+		want interface{}
+	}{
+		{"tf", func(r *Result, key string) (interface{}, error) { return r.String(key) }, "Wallace"},
+		{"empty", func(r *Result, key string) (interface{}, error) { return r.String(key) }, ""},
+		{"cb", func(r *Result, key string) (interface{}, error) { return r.Bool(key) }, true},
+		{"off", func(r *Result, key string) (interface{}, error) { return r.Bool(key) }, false},
+		{"empty", func(r *Result, key string) (interface{}, error) { return r.Bool(key) }, false},
+		{"n", func(r *Result, key string) (interface{}, error) { return r.Int(key) }, 42},
+		{"f", func(r *Result, key string) (interface{}, error) { return r.Float(key) }, 0.5},
+		{"n", func(r *Result, key string) (interface{}, error) { return r.Float(key) }, 42.0},
+		{"date", func(r *Result, key string) (interface{}, error) { return r.Time(key) },
+			time.Date(2024, 2, 29, 13, 45, 0, 0, time.Local)},
+		{"day", func(r *Result, key string) (interface{}, error) { return r.Time(key) },
+			time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		got, err := test.get(r, test.key)
+		if err != nil || got != test.want {
+			t.Errorf("%s: got %v, %v, want %v", test.key, got, err, test.want)
+		}
+	}
+}
+
+func TestResultAccessorErrors(t *testing.T) {
+	r := NewResult(nil, map[string]string{"bad": "x", "f": "0.5", "two": "2"})
+	accessors := map[string]func(key string) error{
+		"String": func(key string) error { _, err := r.String(key); return err },
+		"Bool":   func(key string) error { _, err := r.Bool(key); return err },
+		"Int":    func(key string) error { _, err := r.Int(key); return err },
+		"Float":  func(key string) error { _, err := r.Float(key); return err },
+		"Time":   func(key string) error { _, err := r.Time(key); return err },
+	}
+	for name, get := range accessors {
+		if err := get("missing"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%s(missing): error %v, want ErrKeyNotFound", name, err)
+		}
+	}
+	tests := []struct {
+		accessor string
+		key      string
+	}{
+		{"Bool", "bad"},
+		{"Bool", "two"},
+		{"Int", "bad"},
+		{"Int", "f"},
+		{"Float", "bad"},
+		{"Time", "bad"},
+		{"Time", "two"},
+	}
+	for _, test := range tests {
+		err := accessors[test.accessor](test.key)
+		if err == nil || errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%s(%s): error %v, want a malformed value error", test.accessor, test.key, err)
+		}
+	}
+}
+
+func TestResultButtons(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    map[string]string
+		cancelled bool
+		clicked   string
+	}{
+		{"default button", map[string]string{"tf": "x", "help": "0", "cancel": "0", "ok": "1"}, false, "ok"},
+		{"cancel button", map[string]string{"tf": "", "help": "0", "cancel": "1", "ok": "0"}, true, "cancel"},
+		{"other button", map[string]string{"help": "1", "cancel": "0", "ok": "0"}, false, "help"},
+		{"autoclose", map[string]string{"tf": "x", "cb": "1", "help": "0", "cancel": "0", "ok": "0"}, false, ""},
+		{"no values", nil, false, ""},
+	}
+	for _, test := range tests {
+		r := NewResult(resultWindow(), test.values)
+		if got := r.Cancelled(); got != test.cancelled {
+			t.Errorf("%s: Cancelled = %v", test.name, got)
+		}
+		clicked, ok := r.ClickedButton()
+		if clicked != test.clicked || ok != (test.clicked != "") {
+			t.Errorf("%s: ClickedButton = %q, %v, want %q", test.name, clicked, ok, test.clicked)
+		}
+	}
+}
+
+func TestNewResult(t *testing.T) {
+	win := resultWindow()
+	win.Elements.Add("nil", (*PashuaButton)(nil))
+	r := NewResult(win, nil)
+	if r.Raw() == nil {
+		t.Error("Raw is nil for a result without values")
+	}
+	if r.cancel != "cancel" || len(r.buttons) != 3 {
+		t.Errorf("cancel = %q, buttons = %q", r.cancel, r.buttons)
+	}
+	if r := NewResult(nil, map[string]string{"cancel": "1"}); r.Cancelled() {
+		t.Error("a result without window knows no cancel button")
+	}
+}
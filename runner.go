@@ -0,0 +1,231 @@
+package pashua
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner executes Pashua for a configuration and returns the raw output
+// Pashua wrote to stdout. Implementations other than ExecRunner make it
+// possible to test code using this package without Pashua
+type Runner interface {
+	Run(ctx context.Context, config string) (string, error)
+}
+
+// RunnerFunc is an adapter to use an ordinary function as a Runner
+type RunnerFunc func(ctx context.Context, config string) (string, error)
+
+// Run calls f(ctx, config)
+func (f RunnerFunc) Run(ctx context.Context, config string) (string, error) {
+	return f(ctx, config)
+}
+
+// StreamRunner is implemented by runners that can write a configuration
+// to Pashua while it is encoded, such as ExecRunner. Clients use RunFrom
+// instead of Run for such runners, so that large windows are never
+// built in memory as a whole
+type StreamRunner interface {
+	Runner
+	RunFrom(ctx context.Context, config io.WriterTo) (string, error)
+}
+
+// configString is a configuration that is already encoded
+type configString string
+
+// WriteTo writes the configuration to w
+func (s configString) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, string(s))
+	return int64(n), err
+}
+
+// windowConfig writes the configuration of a window for a Pashua version
+type windowConfig struct {
+	win    *PashuaWindow
+	target *versionTarget
+}
+
+// WriteTo encodes the window to w
+func (c windowConfig) WriteTo(w io.Writer) (int64, error) {
+	return c.win.writeConfig(w, false, c.target)
+}
+
+// DefaultRunner is used by RunPashua, RunPashuaWithStruct and the
+// other package functions if it is not nil. The pashuaPath passed to
+// these functions is ignored then. Set it to a FakeRunner in tests
+var DefaultRunner Runner
+
+// ExecRunner is the Runner that executes the Pashua binary.
+// Path is passed to LocatePashua to find the binary, Env holds
+// additional "KEY=value" environment variables for the process
+type ExecRunner struct {
+	Path string
+	Env  []string
+	// Executable is the binary that has already been located, it is
+	// executed without searching and Path is ignored then
+	Executable string
+}
+
+// Run locates and starts Pashua, writes the configuration to its stdin
+// and returns its stdout. Pashua is killed when ctx is cancelled or its
+// deadline passes, the returned error matches ErrCancelled or ErrTimeout
+// and the context error then. If Pashua fails, the error is an *ExitError
+func (r *ExecRunner) Run(ctx context.Context, config string) (string, error) {
+	return r.RunFrom(ctx, configString(config))
+}
+
+// RunFrom works like Run, but lets config write itself to the stdin of
+// Pashua. If config fails with an error of its own, Pashua is killed
+// and the error is returned. The configuration is written a second
+// time into the *ExitError if Pashua fails
+func (r *ExecRunner) RunFrom(ctx context.Context, config io.WriterTo) (string, error) {
+	appPath := r.Executable
+	if appPath == "" {
+		var err error
+		if appPath, err = locatePashua(r.Path, nil); err != nil {
+			return "", err
+		}
+	}
+	cmd := exec.CommandContext(ctx, appPath, "-")
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// do not wait forever for the output pipes once Pashua has been killed
+	cmd.WaitDelay = time.Second
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", &ExitError{Code: -1, Err: err, Config: configText(config)}
+	}
+	stdin := &pipeWriter{w: pipe}
+	_, writeErr := config.WriteTo(stdin)
+	if writeErr != nil && stdin.err == nil {
+		// the configuration could not be encoded: kill Pashua before
+		// closing stdin, so that it never shows a partial dialog
+		cmd.Process.Kill()
+		pipe.Close()
+		cmd.Wait()
+		return "", writeErr
+	}
+	pipe.Close()
+	// Wait reaps the process, so a killed Pashua does not become a zombie.
+	// A failed write means that Pashua exited early, its exit status tells why
+	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", newStoppedError(ctxErr)
+	}
+	if err != nil {
+		code := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		text := configText(config)
+		return "", &ExitError{
+			Code:   code,
+			Stderr: stderr.String(),
+			Config: text,
+			Err:    err,
+		}
+	}
+	return stdout.String(), nil
+}
+
+// pipeWriter remembers the first error writing to the stdin of Pashua,
+// to tell it apart from an error encoding the configuration
+type pipeWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *pipeWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return n, err
+}
+
+// configText returns the configuration as string for error reports
+func configText(config io.WriterTo) string {
+	if s, ok := config.(configString); ok {
+		return string(s)
+	}
+	var sb strings.Builder
+	config.WriteTo(&sb)
+	return sb.String()
+}
+
+// FakeRunner is an in-memory Runner for tests. It records every
+// configuration it receives and answers each call with the next entry
+// of Answers, formatted the way Pashua would write it
+type FakeRunner struct {
+	// Answers holds the values returned for each call, in order
+	Answers []map[string]string
+	// Err, if set, is returned by every call instead of an answer
+	Err error
+	// Configs holds the configurations received so far
+	Configs []string
+
+	mu sync.Mutex
+}
+
+// ErrNoAnswer is returned by a FakeRunner that has run out of answers
+var ErrNoAnswer = errors.New("fake runner has no more answers")
+
+// Run records the configuration and returns the next answer
+func (f *FakeRunner) Run(ctx context.Context, config string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Configs = append(f.Configs, config)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", newStoppedError(err)
+	}
+	call := len(f.Configs)
+	if call > len(f.Answers) {
+		return "", fmt.Errorf("%w (call %d)", ErrNoAnswer, call)
+	}
+	return FormatPashuaOutput(f.Answers[call-1]), nil
+}
+
+// LastConfig returns the last configuration received or an empty string
+func (f *FakeRunner) LastConfig() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.Configs) == 0 {
+		return ""
+	}
+	return f.Configs[len(f.Configs)-1]
+}
+
+// FormatPashuaOutput writes values the way Pashua writes them to stdout,
+// one key=value line per entry sorted by key. It is the inverse of
+// ParsePashuaOutput
+func FormatPashuaOutput(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key + "=" + escapeValue(values[key]) + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,208 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// failingConfig writes part of a configuration and then fails
+type failingConfig struct{}
+
+func (failingConfig) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, "tf.type=textfield\n")
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), errors.New("cannot encode element")
+}
+
+func TestExecRunnerKillsBeforeClosingStdin(t *testing.T) {
+	dir := t.TempDir()
+	shown := filepath.Join(dir, "shown")
+	script := filepath.Join(dir, "Pashua")
+	// the dialog is "shown" once the whole configuration has been read
+	content := "#!/bin/sh\ncat > /dev/null\ntouch " + shown + "\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := &ExecRunner{Executable: script}
+	_, err := r.RunFrom(context.Background(), failingConfig{})
+	if err == nil || err.Error() != "cannot encode element" {
+		t.Fatalf("error %v, want the encoding error", err)
+	}
+	if _, err := os.Stat(shown); err == nil {
+		t.Error("Pashua read the end of a partial configuration")
+	}
+}
+
+func TestExecRunnerRunsExecutable(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "Pashua")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nread line\necho \"tf=$line\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := &ExecRunner{Path: "/does/not/exist", Executable: script}
+	output, err := r.Run(context.Background(), "tf.type=textfield\n")
+	if err != nil || output != "tf=tf.type=textfield\n" {
+		t.Errorf("Run = %q, %v", output, err)
+	}
+}
+
+// sleepingPashua writes a Pashua that reads its configuration, records
+// its process ID and then sleeps. It returns the executable and a
+// function waiting for the process ID
+func sleepingPashua(t *testing.T) (string, func() int) {
+	t.Helper()
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "pid")
+	script := filepath.Join(dir, "Pashua")
+	content := "#!/bin/sh\ncat > /dev/null\necho $$ > " + pidFile + ".tmp\nmv " + pidFile + ".tmp " + pidFile + "\nexec sleep 30\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script, func() int {
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+			if data, err := os.ReadFile(pidFile); err == nil {
+				pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return pid
+			}
+		}
+		t.Fatal("Pashua did not start")
+		return 0
+	}
+}
+
+// reaped reports whether the process with the given ID is gone,
+// a zombie would still accept signal 0
+func reaped(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) != nil
+}
+
+func TestRunPashuaContextKillsOnCancel(t *testing.T) {
+	script, waitPID := sleepingPashua(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pid := make(chan int, 1)
+	go func() {
+		pid <- waitPID()
+		cancel()
+	}()
+	start := time.Now()
+	_, err := RunPashuaContext(ctx, "tf.type=textfield\n", script)
+	if !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("error %v, want ErrCancelled and context.Canceled", err)
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("error %v matches ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("returned after %v, Pashua was not killed", elapsed)
+	}
+	if p := <-pid; !reaped(p) {
+		t.Errorf("process %d was not reaped", p)
+	}
+}
+
+func TestRunPashuaWithStructContextKillsOnDeadline(t *testing.T) {
+	script, waitPID := sleepingPashua(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	win := &PashuaWindow{}
+	win.Elements.Add("tf", PashuaTextField{})
+	_, err := RunPashuaWithStructContext(ctx, win, script)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error %v, want ErrTimeout and context.DeadlineExceeded", err)
+	}
+	if errors.Is(err, ErrCancelled) {
+		t.Errorf("error %v matches ErrCancelled", err)
+	}
+	if p := waitPID(); !reaped(p) {
+		t.Errorf("process %d was not reaped", p)
+	}
+}
+
+func TestExecRunnerRunFromCancelled(t *testing.T) {
+	script, waitPID := sleepingPashua(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitPID()
+		cancel()
+	}()
+	r := &ExecRunner{Executable: script}
+	output, err := r.RunFrom(ctx, configString("tf.type=textfield\n"))
+	if output != "" || !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("RunFrom = %q, %v, want ErrCancelled", output, err)
+	}
+	if p := waitPID(); !reaped(p) {
+		t.Errorf("process %d was not reaped", p)
+	}
+}
+
+func TestFakeRunner(t *testing.T) {
+	f := &FakeRunner{Answers: []map[string]string{{"tf": "Wallace"}, {"tf": "line 1\nline 2", "ok": "1"}}}
+	if got := f.LastConfig(); got != "" {
+		t.Errorf("LastConfig before any run = %q", got)
+	}
+	configs := []string{"tf.type=textfield\n", "tf.type=textfield\nok.type=defaultbutton\n", "*.title=Third\n"}
+	for i, want := range []map[string]string{{"tf": "Wallace"}, {"tf": "line 1\nline 2", "ok": "1"}} {
+		got, err := RunPashuaWithRunner(context.Background(), f, configs[i])
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("call %d = %q, %v, want %q", i+1, got, err, want)
+		}
+		if last := f.LastConfig(); last != configs[i] {
+			t.Errorf("call %d: LastConfig = %q, want %q", i+1, last, configs[i])
+		}
+	}
+	if _, err := f.Run(context.Background(), configs[2]); !errors.Is(err, ErrNoAnswer) || !strings.Contains(err.Error(), "call 3") {
+		t.Errorf("call 3: error %v, want ErrNoAnswer", err)
+	}
+	if !reflect.DeepEqual(f.Configs, configs) {
+		t.Errorf("Configs = %q, want %q", f.Configs, configs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&FakeRunner{Answers: f.Answers}).Run(ctx, ""); !errors.Is(err, ErrCancelled) {
+		t.Errorf("cancelled context: error %v, want ErrCancelled", err)
+	}
+	failure := errors.New("boom")
+	if _, err := (&FakeRunner{Answers: f.Answers, Err: failure}).Run(context.Background(), ""); err != failure {
+		t.Errorf("Err: error %v, want %v", err, failure)
+	}
+}
+
+func TestRunPashuaWithStructUsesDefaultRunner(t *testing.T) {
+	runner := &FakeRunner{Answers: []map[string]string{{"tf": "Gromit", "ok": "1"}}}
+	defer func(previous Runner) { DefaultRunner = previous }(DefaultRunner)
+	DefaultRunner = runner
+
+	win := &PashuaWindow{Title: "Settings"}
+	win.Elements.Add("tf", PashuaTextField{Label: "Name"})
+	win.Elements.Add("ok", PashuaDefaultButton{})
+	got, err := RunPashuaWithStruct(win, "/does/not/exist")
+	if want := map[string]string{"tf": "Gromit", "ok": "1"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("RunPashuaWithStruct = %q, %v, want %q", got, err, want)
+	}
+	config, err := win.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := runner.LastConfig(); last != config {
+		t.Errorf("DefaultRunner received %q, want %q", last, config)
+	}
+}
@@ -0,0 +1,267 @@
+package pashua
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the format Pashua uses for date and time values
+const dateLayout = "2006-01-02 15:04"
+
+// dateLayouts lists the formats a date element may return,
+// depending on whether it shows the date, the time or both
+var dateLayouts = []string{dateLayout, "2006-01-02 15:04:05", "2006-01-02", "15:04"}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structField is a struct field with a "pashua" tag
+type structField struct {
+	index   int
+	name    string
+	key     string
+	typ     string
+	options []string
+}
+
+// WindowFromStruct builds a PashuaWindow from the tagged fields of the struct v
+// points to. The tag starts with the Pashua element type, followed by a comma
+// separated list of attributes, for example:
+//
+//	Name string `pashua:"textfield,label=Name,mandatory"`
+//	Kind string `pashua:"popup,label=Kind,options=small|medium|large"`
+//
+// Attributes without a value (such as "mandatory") are set to true, "options"
+// takes a list separated by "|" and "key" overrides the element key, which
+// defaults to the lower-case field name. The current value of each field is used
+// as the default of its element. Fields without a tag or with the tag "-" are skipped.
+// Window attributes such as the title can be set on the returned window
+func WindowFromStruct(v interface{}) (*PashuaWindow, error) {
+	rv, fields, err := taggedFields(v)
+	if err != nil {
+		return nil, err
+	}
+	win := &PashuaWindow{}
+	for _, field := range fields {
+		comp := newComponent(field.typ)
+		if comp == nil {
+			return nil, fmt.Errorf("field %s: unknown element type %q", field.name, field.typ)
+		}
+		attrs := componentFields(comp)
+		if err := setStructDefault(attrs, field.typ, rv.Field(field.index)); err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.name, err)
+		}
+		for _, opt := range field.options {
+			name, value := opt, "1"
+			if pos := strings.Index(opt, "="); pos >= 0 {
+				name, value = opt[:pos], opt[pos+1:]
+			}
+			if name == "key" {
+				continue
+			}
+			if name == "options" {
+				name = "option"
+			}
+			if field.typ == "textbox" && name == "fixed" {
+				name, value = "fonttype", "fixed"
+			}
+			attr, found := attrs[name]
+			if !found {
+				return nil, fmt.Errorf("field %s: unknown attribute %q for type %s", field.name, name, field.typ)
+			}
+			values := []string{value}
+			if name == "option" {
+				values = strings.Split(value, "|")
+			}
+			for _, value := range values {
+				if err := setField(attr, value); err != nil {
+					return nil, fmt.Errorf("field %s: attribute %q: %v", field.name, name, err)
+				}
+			}
+			keepZero(comp, name, attr)
+		}
+		if field.typ == "date" && !hasOption(field.options, "date") && !hasOption(field.options, "time") {
+			setField(attrs["date"], "1")
+		}
+		win.Elements.Add(field.key, reflect.ValueOf(comp).Elem().Interface())
+	}
+	return win, nil
+}
+
+// DecodeResult stores the values returned by Pashua in the tagged fields
+// of the struct v points to, converting them to the type of each field.
+// Supported are strings, bools, all integer and float types, time.Time and
+// string slices (one entry per line). Keys missing from the result are skipped
+func DecodeResult(result map[string]string, v interface{}) error {
+	rv, fields, err := taggedFields(v)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		value, found := result[field.key]
+		if !found {
+			continue
+		}
+		if err := setStructValue(rv.Field(field.index), value); err != nil {
+			return fmt.Errorf("field %s: %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+// taggedFields returns the struct v points to and its fields with a "pashua" tag
+func taggedFields(v interface{}) (reflect.Value, []structField, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("expected a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	fields := []structField{}
+	for i := 0; i < rv.NumField(); i++ {
+		sf := rv.Type().Field(i)
+		tag, found := sf.Tag.Lookup("pashua")
+		if !found || tag == "-" || sf.PkgPath != "" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		field := structField{
+			index:   i,
+			name:    sf.Name,
+			key:     strings.ToLower(sf.Name),
+			typ:     strings.TrimSpace(parts[0]),
+			options: parts[1:],
+		}
+		for _, opt := range field.options {
+			if strings.HasPrefix(opt, "key=") {
+				field.key = opt[len("key="):]
+			}
+		}
+		fields = append(fields, field)
+	}
+	return rv, fields, nil
+}
+
+// hasOption returns true if the tag options contain the given flag
+func hasOption(options []string, name string) bool {
+	for _, opt := range options {
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setStructDefault uses the current value of a struct field as the default
+// of the element. Text elements show the value, images use it as path
+func setStructDefault(attrs map[string]interface{}, typ string, fv reflect.Value) error {
+	name := "default"
+	switch typ {
+	case "text":
+		name = "text"
+	case "image":
+		name = "path"
+	}
+	attr, found := attrs[name]
+	if !found || fv.IsZero() {
+		return nil
+	}
+	value, err := formatStructValue(fv)
+	if err != nil {
+		return err
+	}
+	return setField(attr, value)
+}
+
+// formatStructValue converts a struct field to the string Pashua expects,
+// string slices become one line per entry, other types are encoded like
+// attribute values
+func formatStructValue(fv reflect.Value) (string, error) {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String {
+		lines := make([]string, fv.Len())
+		for i := range lines {
+			lines[i] = fv.Index(i).String()
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	return encodeValue(fv.Interface())
+}
+
+// setStructValue converts a value returned by Pashua to the type of the field.
+// An empty value sets numbers and times to their zero value
+func setStructValue(fv reflect.Value, value string) error {
+	if fv.Type() == timeType {
+		if value == "" {
+			fv.Set(reflect.Zero(timeType))
+			return nil
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	var err error
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		fv.SetBool(value == "1")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if value != "" {
+			i, err = strconv.ParseInt(strings.TrimSpace(value), 10, fv.Type().Bits())
+		}
+		if err == nil {
+			fv.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if value != "" {
+			u, err = strconv.ParseUint(strings.TrimSpace(value), 10, fv.Type().Bits())
+		}
+		if err == nil {
+			fv.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if value != "" {
+			f, err = strconv.ParseFloat(strings.TrimSpace(value), fv.Type().Bits())
+		}
+		if err == nil {
+			fv.SetFloat(f)
+		}
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		lines := []string{}
+		if value != "" {
+			lines = strings.Split(value, "\n")
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(lines), len(lines))
+		for i, line := range lines {
+			slice.Index(i).SetString(line)
+		}
+		fv.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q for type %s", value, fv.Type())
+	}
+	return nil
+}
+
+// parseTime parses a date returned by Pashua, trying all known layouts
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,94 @@
+package pashua
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWindowFromStructDates(t *testing.T) {
+	s := struct {
+		Day    string `pashua:"date,label=Day"`
+		Clock  string `pashua:"date,time"`
+		Both   string `pashua:"date,date,time"`
+		Option bool   `pashua:"checkbox,label=Option"`
+	}{}
+	win, err := WindowFromStruct(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := win.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "day.type=date\nday.label=Day\nday.date=1\n" +
+		"clock.type=date\nclock.date=0\nclock.time=1\n" +
+		"both.type=date\nboth.date=1\nboth.time=1\n" +
+		"option.type=checkbox\noption.label=Option"
+	if config != want {
+		t.Errorf("config =\n%s\nwant\n%s", config, want)
+	}
+	if got := DefaultAnswers(win)["option"]; got != "0" {
+		t.Errorf("unchecked checkbox answers %q, want \"0\"", got)
+	}
+}
+
+func TestWindowFromStructDefaults(t *testing.T) {
+	s := struct {
+		Name  string    `pashua:"textfield"`
+		Ratio float64   `pashua:"textfield"`
+		Count uint16    `pashua:"textfield"`
+		When  time.Time `pashua:"date,date,time"`
+		Lines []string  `pashua:"textbox"`
+		Note  string    `pashua:"text"`
+	}{"Wallace", 0.5, 12, time.Date(2024, 2, 29, 13, 5, 0, 0, time.UTC), []string{"a", "b"}, "Hello"}
+	win, err := WindowFromStruct(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"name": "Wallace", "ratio": "0.5000", "count": "12",
+		"when": "2024-02-29 13:05", "lines": "a\nb", "note": "",
+	}
+	got := map[string]string{}
+	doc, err := win.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range doc.Elements {
+		got[elem.Key], _ = elem.Get("default")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults = %q, want %q", got, want)
+	}
+	if text, _ := doc.Elements[5].Get("text"); text != "Hello" {
+		t.Errorf("text = %q, want the field value", text)
+	}
+}
+
+func TestDecodeResult(t *testing.T) {
+	s := struct {
+		Name  string    `pashua:"textfield"`
+		Debug bool      `pashua:"checkbox"`
+		Count int       `pashua:"textfield"`
+		Ratio float32   `pashua:"textfield"`
+		When  time.Time `pashua:"date"`
+		Lines []string  `pashua:"textbox,key=tb"`
+		Skip  string
+	}{Skip: "kept"}
+	result := map[string]string{
+		"name": "Gromit", "debug": "1", "count": " 42", "ratio": "0.25",
+		"when": "2024-02-29 13:05", "tb": "a\nb", "skip": "changed",
+	}
+	if err := DecodeResult(result, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Gromit" || !s.Debug || s.Count != 42 || s.Ratio != 0.25 ||
+		!s.When.Equal(time.Date(2024, 2, 29, 13, 5, 0, 0, time.Local)) ||
+		!reflect.DeepEqual(s.Lines, []string{"a", "b"}) || s.Skip != "kept" {
+		t.Errorf("decoded %+v", s)
+	}
+	if err := DecodeResult(map[string]string{"count": "many"}, &s); err == nil {
+		t.Error("invalid number: expected an error")
+	}
+}
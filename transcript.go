@@ -0,0 +1,279 @@
+package pashua
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TranscriptEntry is a single dialog of a recorded session:
+// the configuration sent to Pashua and what Pashua returned
+type TranscriptEntry struct {
+	Config string `json:"config"`
+	Output string `json:"output"`
+	// Error is the message of the returned error, for an *ExitError
+	// the message of the error it wraps
+	Error string `json:"error,omitempty"`
+	// ErrorKind is one of the ErrorKind* constants, so that the replayed
+	// error matches the same sentinel errors and types
+	ErrorKind string `json:"error_kind,omitempty"`
+	// ExitCode and Stderr are recorded for an *ExitError
+	ExitCode int    `json:"exit_code,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+// Kinds of errors recorded in a TranscriptEntry
+const (
+	ErrorKindCancelled = "cancelled"
+	ErrorKindTimeout   = "timeout"
+	ErrorKindNotFound  = "notfound"
+	ErrorKindExit      = "exit"
+)
+
+// setError records err in the entry
+func (entry *TranscriptEntry) setError(err error) {
+	entry.Error = err.Error()
+	var exitErr *ExitError
+	switch {
+	case errors.Is(err, ErrCancelled):
+		entry.ErrorKind = ErrorKindCancelled
+	case errors.Is(err, ErrTimeout):
+		entry.ErrorKind = ErrorKindTimeout
+	case errors.Is(err, ErrPashuaNotFound):
+		entry.ErrorKind = ErrorKindNotFound
+	case errors.As(err, &exitErr):
+		entry.ErrorKind = ErrorKindExit
+		entry.ExitCode = exitErr.Code
+		entry.Stderr = exitErr.Stderr
+		if exitErr.Err != nil {
+			entry.Error = exitErr.Err.Error()
+		}
+	}
+}
+
+// err rebuilds the recorded error, nil if there is none. Errors
+// without a known kind are returned with their message only
+func (entry *TranscriptEntry) err() error {
+	if entry.Error == "" && entry.ErrorKind == "" {
+		return nil
+	}
+	switch entry.ErrorKind {
+	case ErrorKindCancelled:
+		return newStoppedError(context.Canceled)
+	case ErrorKindTimeout:
+		return newStoppedError(context.DeadlineExceeded)
+	case ErrorKindNotFound:
+		return &replayedError{msg: entry.Error, kind: ErrPashuaNotFound}
+	case ErrorKindExit:
+		return &ExitError{
+			Code:   entry.ExitCode,
+			Stderr: entry.Stderr,
+			Config: entry.Config,
+			Err:    errors.New(entry.Error),
+		}
+	}
+	return errors.New(entry.Error)
+}
+
+// replayedError keeps the recorded message of an error
+// and matches the sentinel error of its kind
+type replayedError struct {
+	msg  string
+	kind error
+}
+
+func (e *replayedError) Error() string {
+	return e.msg
+}
+
+func (e *replayedError) Unwrap() error {
+	return e.kind
+}
+
+// RecordingRunner wraps a Runner (usually the ExecRunner on a Mac) and
+// appends every dialog to a transcript file, one JSON object per line.
+// The transcript can be served again by a ReplayRunner
+type RecordingRunner struct {
+	// Runner runs the dialogs, an ExecRunner if nil
+	Runner Runner
+	// Path is the name of the transcript file
+	Path string
+
+	mu sync.Mutex
+}
+
+// Run runs the dialog with the wrapped Runner and records it
+func (r *RecordingRunner) Run(ctx context.Context, config string) (string, error) {
+	runner := r.Runner
+	if runner == nil {
+		runner = &ExecRunner{}
+	}
+	output, err := runner.Run(ctx, config)
+	entry := TranscriptEntry{Config: config, Output: output}
+	if err != nil {
+		entry.setError(err)
+	}
+	if recErr := r.record(entry); recErr != nil && err == nil {
+		err = fmt.Errorf("recording transcript: %w", recErr)
+	}
+	return output, err
+}
+
+// record appends an entry to the transcript file
+func (r *RecordingRunner) record(entry TranscriptEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadTranscript reads a transcript file written by a RecordingRunner
+func LoadTranscript(filename string) ([]TranscriptEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []TranscriptEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	number := 0
+	for scanner.Scan() {
+		number++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		entry := TranscriptEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, number, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ErrTranscriptExhausted is returned by a ReplayRunner
+// that has served all recorded dialogs
+var ErrTranscriptExhausted = errors.New("no more dialogs in transcript")
+
+// ConfigMismatchError is returned by a ReplayRunner if the configuration
+// differs from the recorded one
+type ConfigMismatchError struct {
+	// Index is the position of the dialog in the transcript
+	Index int
+	// Diff lists the differing lines, "-" recorded and "+" received
+	Diff string
+}
+
+func (e *ConfigMismatchError) Error() string {
+	return fmt.Sprintf("dialog %d does not match the transcript:\n%s", e.Index, e.Diff)
+}
+
+// ReplayRunner serves the dialogs of a transcript in order, checking
+// that each configuration matches the recorded one
+type ReplayRunner struct {
+	Entries []TranscriptEntry
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewReplayRunner creates a ReplayRunner for a transcript file
+func NewReplayRunner(filename string) (*ReplayRunner, error) {
+	entries, err := LoadTranscript(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayRunner{Entries: entries}, nil
+}
+
+// Run returns the recorded output of the next dialog, or a
+// *ConfigMismatchError if the configuration is not the recorded one.
+// A recorded error is returned again, matching ErrCancelled, ErrTimeout,
+// ErrPashuaNotFound or *ExitError like the original
+func (r *ReplayRunner) Run(ctx context.Context, config string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", newStoppedError(err)
+	}
+	if r.next >= len(r.Entries) {
+		return "", ErrTranscriptExhausted
+	}
+	index := r.next
+	entry := r.Entries[index]
+	r.next++
+	if entry.Config != config {
+		return "", &ConfigMismatchError{Index: index, Diff: ConfigDiff(entry.Config, config)}
+	}
+	return entry.Output, entry.err()
+}
+
+// Remaining returns the number of dialogs not yet served
+func (r *ReplayRunner) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Entries) - r.next
+}
+
+// ConfigDiff compares two configurations line by line and returns the
+// differences: lines only in want start with "- ", lines only in got
+// with "+ ". It returns an empty string if both are equal
+func ConfigDiff(want string, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+	// equal lines are not reported, so the table is only needed
+	// for the lines between the common prefix and suffix
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	// lcs[i*w+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	w := len(b) + 1
+	lcs := make([]int, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+				lcs[i*w+j] = lcs[(i+1)*w+j]
+			default:
+				lcs[i*w+j] = lcs[i*w+j+1]
+			}
+		}
+	}
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+package pashua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scriptedRunner returns the same output and error for every dialog
+type scriptedRunner struct {
+	output string
+	err    error
+}
+
+func (r *scriptedRunner) Run(ctx context.Context, config string) (string, error) {
+	return r.output, r.err
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	config := "tf.type=textfield\ntf.colour=red"
+	exitErr := &ExitError{Code: 2, Stderr: `Error: line 2: unknown attribute "colour" for element "tf"`, Config: config, Err: errors.New("exit status 2")}
+	errs := []error{
+		nil,
+		exitErr,
+		newStoppedError(context.Canceled),
+		newStoppedError(context.DeadlineExceeded),
+		&NotFoundError{},
+		errors.New("something else"),
+	}
+	filename := filepath.Join(t.TempDir(), "session.jsonl")
+	for _, err := range errs {
+		rec := &RecordingRunner{Runner: &scriptedRunner{output: "tf=x\n", err: err}, Path: filename}
+		if _, got := rec.Run(context.Background(), config); got != err {
+			t.Fatalf("recording returned %v, want %v", got, err)
+		}
+	}
+
+	replay, err := NewReplayRunner(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func() error {
+		output, err := replay.Run(context.Background(), config)
+		if output != "tf=x\n" {
+			t.Errorf("output = %q", output)
+		}
+		return err
+	}
+	if err := run(); err != nil {
+		t.Errorf("dialog 0: %v", err)
+	}
+	var replayed *ExitError
+	if err := run(); !errors.As(err, &replayed) || replayed.Code != 2 || replayed.Stderr != exitErr.Stderr ||
+		replayed.Err.Error() != "exit status 2" || err.Error() != exitErr.Error() {
+		t.Errorf("dialog 1: %#v, want an *ExitError like %#v", err, exitErr)
+	}
+	if err := run(); !errors.Is(err, ErrCancelled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("dialog 2: %v, want ErrCancelled", err)
+	}
+	if err := run(); !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("dialog 3: %v, want ErrTimeout", err)
+	}
+	if err := run(); !errors.Is(err, ErrPashuaNotFound) || err.Error() != errs[4].Error() {
+		t.Errorf("dialog 4: %v, want ErrPashuaNotFound", err)
+	}
+	if err := run(); err == nil || err.Error() != "something else" {
+		t.Errorf("dialog 5: %v", err)
+	}
+	if _, err := replay.Run(context.Background(), config); !errors.Is(err, ErrTranscriptExhausted) {
+		t.Errorf("after the transcript: %v, want ErrTranscriptExhausted", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	replay := &ReplayRunner{Entries: []TranscriptEntry{{Config: "a.type=text\na.text=old", Output: ""}}}
+	_, err := replay.Run(context.Background(), "a.type=text\na.text=new")
+	var mismatch *ConfigMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Diff != "- a.text=old\n+ a.text=new\n" {
+		t.Errorf("error %v, want a *ConfigMismatchError", err)
+	}
+	if !strings.Contains(err.Error(), "dialog 0") {
+		t.Errorf("message %q does not name the dialog", err)
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	tests := []struct {
+		want, got string
+		diff      string
+	}{
+		{"a\nb\nc", "a\nb\nc", ""},
+		{"a\nb\nc", "a\nx\nc", "- b\n+ x\n"},
+		{"a\nb\nc", "a\nc", "- b\n"},
+		{"a\nc", "a\nb\nc", "+ b\n"},
+		{"a\nb", "b\na", "- a\n+ a\n"},
+		{"", "a", "- \n+ a\n"},
+	}
+	for _, test := range tests {
+		if diff := ConfigDiff(test.want, test.got); diff != test.diff {
+			t.Errorf("ConfigDiff(%q, %q) = %q, want %q", test.want, test.got, diff, test.diff)
+		}
+	}
+}
+
+func TestConfigDiffLargeConfig(t *testing.T) {
+	lines := make([]string, 20000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("tf%d.type=textfield", i)
+	}
+	want := strings.Join(lines, "\n")
+	lines[10000] = "tf10000.type=password"
+	got := strings.Join(lines, "\n")
+	// a full table would need 20001*20001 ints, about 3 GB
+	if diff := ConfigDiff(want, got); diff != "- tf10000.type=textfield\n+ tf10000.type=password\n" {
+		t.Errorf("diff = %q", diff)
+	}
+}
@@ -0,0 +1,205 @@
+package pashua
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validKey matches the element keys Pashua accepts
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ValidationError is returned by Validate and
+// holds every problem found in a window
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid pashua window: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the single errors, so that errors.Is
+// and errors.As can be used on a ValidationError
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// Validate checks the window for mistakes that Pashua would only report
+// with a cryptic message or that would result in a broken dialog. It returns
+// nil or a *ValidationError listing all problems found
+func (win *PashuaWindow) Validate() error {
+	return win.validate(true)
+}
+
+// validate implements Validate, checkImages tells
+// whether image files must exist
+func (win *PashuaWindow) validate(checkImages bool) error {
+	errs := []error{}
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if win.Transparency < 0 || win.Transparency > 1 {
+		fail("window transparency %v is not between 0 and 1", win.Transparency)
+	}
+	if win.AutoCloseTime < 0 {
+		fail("window autoclosetime %d is negative", win.AutoCloseTime)
+	}
+	for _, name := range sortedNames(win.Explicit) {
+		// only the position of the window is written if zero
+		if name != "x" && name != "y" {
+			fail("window: Explicit attribute %q is not x or y", name)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, elem := range win.Elements {
+		if seen[elem.Key] {
+			fail("element %q: duplicate key", elem.Key)
+		}
+		seen[elem.Key] = true
+		if _, found := win.Components[elem.Key]; found {
+			fail("element %q: key used in both Elements and Components", elem.Key)
+		}
+	}
+	types := make(map[string][]string)
+	for _, elem := range win.allElements() {
+		if !validKey.MatchString(elem.Key) {
+			fail("element %q: key may only contain letters, digits and underscores", elem.Key)
+		}
+		comp, ok := elem.Component.(Component)
+		if !ok {
+			fail("element %q: type %T does not implement pashua.Component", elem.Key, elem.Component)
+			continue
+		}
+		if isNilPointer(comp) {
+			fail("element %q: component is a nil pointer", elem.Key)
+			continue
+		}
+		docElem, err := NewDocumentElement(elem.Key, comp)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		types[docElem.Type] = append(types[docElem.Type], elem.Key)
+		if t := builtinFields(comp); t != nil {
+			for _, name := range unknownExplicit(t) {
+				fail("element %q: Explicit attribute %q is not an attribute of %s", elem.Key, name, docElem.Type)
+			}
+		}
+		for _, msg := range validateElement(docElem, checkImages) {
+			fail("element %q: %s", elem.Key, msg)
+		}
+	}
+	for _, typ := range []string{"defaultbutton", "cancelbutton"} {
+		if len(types[typ]) > 1 {
+			fail("only one %s allowed, found %s", typ, strings.Join(types[typ], ", "))
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// unknownExplicit returns the sorted names in the Explicit set of a
+// built-in element that are not in its attribute table
+func unknownExplicit(t fieldTable) []string {
+	known := make(map[string]bool)
+	for _, f := range t.attributeFields() {
+		known[f.name] = true
+	}
+	unknown := []string{}
+	for _, name := range sortedNames(*t.explicitSet()) {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// sortedNames returns the names in the set in sorted order
+func sortedNames(set AttributeSet) []string {
+	names := make([]string, 0, len(set))
+	for name, ok := range set {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isNilPointer returns true if the component is a nil pointer,
+// calling its methods would panic then
+func isNilPointer(comp interface{}) bool {
+	value := reflect.ValueOf(comp)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+// ValidateElement checks the attributes of a document element and returns
+// a message for each problem found: names that Pashua rejects, sizes that
+// are not a positive number, mandatory elements without label, elements
+// without options, defaults that are not one of the options and missing
+// images. It works the same for the built-in and for custom components
+func ValidateElement(elem DocumentElement) []string {
+	return validateElement(elem, true)
+}
+
+// validateElement implements ValidateElement, checkImages tells
+// whether image files must exist
+func validateElement(elem DocumentElement, checkImages bool) []string {
+	msgs := []string{}
+	if !validKey.MatchString(elem.Type) {
+		msgs = append(msgs, fmt.Sprintf("invalid type %q", elem.Type))
+	}
+	for _, attr := range elem.Attributes {
+		if !validKey.MatchString(attr.Name) {
+			msgs = append(msgs, fmt.Sprintf("invalid attribute name %q", attr.Name))
+		}
+	}
+	for _, name := range []string{"width", "height", "maxwidth", "maxheight", "rows"} {
+		value, found := elem.Get(name)
+		if !found {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		switch {
+		case err != nil:
+			msgs = append(msgs, fmt.Sprintf("%s %q is not a number", name, value))
+		case size < 0:
+			msgs = append(msgs, fmt.Sprintf("%s %d is negative", name, size))
+		}
+	}
+	if mandatory, _ := elem.Get("mandatory"); mandatory == "1" {
+		if label, _ := elem.Get("label"); label == "" {
+			msgs = append(msgs, "mandatory element without label")
+		}
+	}
+	switch elem.Type {
+	case "combobox", "popup", "radiobutton":
+		list := elem.Values("option")
+		if len(list) == 0 {
+			msgs = append(msgs, "no options")
+		}
+		if def, _ := elem.Get("default"); elem.Type != "combobox" && def != "" && !hasOption(list, def) {
+			msgs = append(msgs, fmt.Sprintf("default %q is not one of the options", def))
+		}
+	case "image":
+		if !checkImages {
+			break
+		}
+		path, _ := elem.Get("path")
+		if _, err := os.Stat(path); err != nil {
+			msgs = append(msgs, fmt.Sprintf("image path %q does not exist", path))
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,114 @@
+package pashua
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		win  func(win *PashuaWindow)
+		msg  string
+	}{
+		{"key syntax", func(win *PashuaWindow) {
+			win.Elements.Add("my-field", PashuaTextField{})
+		}, `"my-field": key may only contain`},
+		{"duplicate key", func(win *PashuaWindow) {
+			win.Elements.Add("tf", PashuaTextField{})
+			win.Elements.Add("tf", PashuaTextField{})
+		}, `"tf": duplicate key`},
+		{"key in Elements and Components", func(win *PashuaWindow) {
+			win.Elements.Add("tf", PashuaTextField{})
+			win.Components = PashuaComponents{"tf": PashuaTextField{}}
+		}, "both Elements and Components"},
+		{"duplicate default button", func(win *PashuaWindow) {
+			win.Elements.Add("ok", PashuaDefaultButton{})
+			win.Elements.Add("ok2", PashuaDefaultButton{})
+		}, "only one defaultbutton allowed, found ok, ok2"},
+		{"duplicate cancel button", func(win *PashuaWindow) {
+			win.Elements.Add("c1", PashuaCancelButton{})
+			win.Elements.Add("c2", PashuaCancelButton{})
+		}, "only one cancelbutton allowed, found c1, c2"},
+		{"default not an option", func(win *PashuaWindow) {
+			win.Elements.Add("pop", PashuaPopup{Option: []string{"a", "b"}, Default: "c"})
+		}, `default "c" is not one of the options`},
+		{"empty option list", func(win *PashuaWindow) {
+			win.Elements.Add("rb", PashuaRadioButton{})
+		}, `"rb": no options`},
+		{"transparency below 0", func(win *PashuaWindow) {
+			win.Transparency = -0.1
+		}, "transparency -0.1 is not between 0 and 1"},
+		{"transparency above 1", func(win *PashuaWindow) {
+			win.Transparency = 1.5
+		}, "transparency 1.5 is not between 0 and 1"},
+		{"negative size", func(win *PashuaWindow) {
+			win.Elements.Add("tf", PashuaTextField{Width: -10})
+		}, "width -10 is negative"},
+		{"mandatory without label", func(win *PashuaWindow) {
+			win.Elements.Add("tf", PashuaTextField{Mandatory: true})
+		}, "mandatory element without label"},
+		{"missing image", func(win *PashuaWindow) {
+			win.Elements.Add("img", PashuaImage{Path: "/does/not/exist.png"})
+		}, `image path "/does/not/exist.png" does not exist`},
+		{"unknown Explicit name", func(win *PashuaWindow) {
+			win.Elements.Add("b", PashuaButton{Explicit: Attrs("x", "rel_x")})
+		}, `"b": Explicit attribute "rel_x" is not an attribute of button`},
+		{"Explicit name of another element", func(win *PashuaWindow) {
+			win.Elements.Add("ok", &PashuaDefaultButton{Explicit: Attrs("X")})
+		}, `"ok": Explicit attribute "X" is not an attribute of defaultbutton`},
+		{"unknown window Explicit name", func(win *PashuaWindow) {
+			win.Explicit = Attrs("y", "title")
+		}, `window: Explicit attribute "title" is not x or y`},
+		{"nil component", func(win *PashuaWindow) {
+			win.Elements.Add("b", (*PashuaButton)(nil))
+		}, `"b": component is a nil pointer`},
+	}
+	for _, test := range tests {
+		win := &PashuaWindow{}
+		test.win(win)
+		err := win.Validate()
+		var verr *ValidationError
+		if !errors.As(err, &verr) || len(verr.Errors) != 1 || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: error %v, want one containing %q", test.name, err, test.msg)
+		}
+	}
+}
+
+func TestValidateAcceptsValidWindow(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(image, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	win := &PashuaWindow{Transparency: 1, AutoCloseTime: 10}
+	win.Elements.Add("img", PashuaImage{Path: image})
+	win.Elements.Add("tf", PashuaTextField{Label: "Name", Mandatory: true, Width: 200})
+	win.Elements.Add("pop", PashuaPopup{Option: []string{"a", "b"}, Default: "b"})
+	win.Elements.Add("cb", PashuaCombobox{Option: []string{"a"}, Default: "typed"})
+	win.Elements.Add("cancel", PashuaCancelButton{})
+	win.Components = PashuaComponents{"ok": PashuaDefaultButton{}}
+	if err := win.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunPashuaWithStructNoValidate(t *testing.T) {
+	runner := &FakeRunner{Answers: []map[string]string{{"pop": "c"}, {"pop": "c"}}}
+	defer func(previous Runner) { DefaultRunner = previous }(DefaultRunner)
+	DefaultRunner = runner
+
+	win := &PashuaWindow{}
+	win.Elements.Add("pop", PashuaPopup{Option: []string{"a", "b"}, Default: "c"})
+	var verr *ValidationError
+	if _, err := RunPashuaWithStruct(win, ""); !errors.As(err, &verr) || len(runner.Configs) != 0 {
+		t.Fatalf("validated: error %v after %d runs, want a *ValidationError", err, len(runner.Configs))
+	}
+	win.NoValidate = true
+	values, err := RunPashuaWithStruct(win, "")
+	if err != nil || values["pop"] != "c" || len(runner.Configs) != 1 {
+		t.Errorf("NoValidate: %q, %v after %d runs", values, err, len(runner.Configs))
+	}
+}
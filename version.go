@@ -0,0 +1,189 @@
+package pashua
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the version of a Pashua release, such as 0.11
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a version such as "0.11" or "0.9.4"
+func ParseVersion(s string) (Version, error) {
+	v := Version{}
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	if v.Patch != 0 {
+		return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Compare returns -1, 0 or 1 if v is older than, equal to or newer than o
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast returns true if v is equal to or newer than o
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
+
+// Version reads the version of Pashua from the Info.plist of the app
+// bundle containing the executable found by Locate. The plist is only
+// used if the bundle is Pashua.app or the plist names Pashua, so that
+// the version of a host app bundling Pashua is not taken for Pashua's
+func (l *Locator) Version(executable string) (Version, error) {
+	contents := path.Dir(path.Dir(executable))
+	plist := path.Join(contents, "Info.plist")
+	data, err := l.readFile(plist)
+	if err != nil {
+		return Version{}, err
+	}
+	values := plistStrings(data)
+	inBundle := path.Base(path.Dir(executable)) == "MacOS" && path.Base(contents) == "Contents" &&
+		path.Base(path.Dir(contents)) == "Pashua.app"
+	if !inBundle && !namesPashua(values) {
+		return Version{}, fmt.Errorf("%s does not belong to Pashua", plist)
+	}
+	for _, key := range []string{"CFBundleShortVersionString", "CFBundleVersion"} {
+		if s, found := values[key]; found {
+			return ParseVersion(s)
+		}
+	}
+	return Version{}, fmt.Errorf("no version found in %s", plist)
+}
+
+// namesPashua returns true if the bundle name or identifier of a
+// plist is that of Pashua
+func namesPashua(values map[string]string) bool {
+	if strings.EqualFold(values["CFBundleName"], "Pashua") {
+		return true
+	}
+	id := strings.ToLower(values["CFBundleIdentifier"])
+	return id == "pashua" || strings.HasSuffix(id, ".pashua")
+}
+
+// plistStrings returns the string values of the top-level dictionary
+// of an XML property list, which is all that is needed from Info.plist
+func plistStrings(data []byte) map[string]string {
+	result := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	key, element := "", ""
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return result
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			element = t.Name.Local
+		case xml.EndElement:
+			element = ""
+		case xml.CharData:
+			switch element {
+			case "key":
+				key = string(t)
+			case "string":
+				if key != "" {
+					result[key] = string(t)
+					key = ""
+				}
+			}
+		}
+	}
+}
+
+// UnsupportedAttributeError is returned when encoding for a Pashua
+// version that does not know an attribute that is set
+type UnsupportedAttributeError struct {
+	Key       string
+	Attribute string
+	Required  Version
+	Detected  Version
+}
+
+func (e *UnsupportedAttributeError) Error() string {
+	return fmt.Sprintf("element %q: attribute %q requires Pashua %s, found %s",
+		e.Key, e.Attribute, e.Required, e.Detected)
+}
+
+var (
+	attributeVersionsMu sync.RWMutex
+	// attributeVersions holds the minimum Pashua version for attributes
+	// that older releases reject, keyed by "type.attribute", or by
+	// "*.attribute" for attributes of all element types. It is empty:
+	// the Pashua release notes do not say in which version attributes
+	// were added, and guessed entries would drop attributes that the
+	// installed Pashua understands. Use RegisterAttributeVersion for
+	// versions that are known to fail
+	attributeVersions = map[string]Version{}
+)
+
+// RegisterAttributeVersion sets the minimum Pashua version for an
+// attribute of an element type, use "*" as type for all element types
+func RegisterAttributeVersion(typ string, attr string, v Version) {
+	attributeVersionsMu.Lock()
+	defer attributeVersionsMu.Unlock()
+	attributeVersions[typ+"."+attr] = v
+}
+
+// skip returns true if the attribute of an element is not supported by
+// the target version. In strict mode an *UnsupportedAttributeError is
+// returned instead. A nil target supports all attributes
+func (target *versionTarget) skip(key string, typ string, attr string) (bool, error) {
+	if target == nil {
+		return false, nil
+	}
+	required, found := requiredVersion(typ, attr)
+	if !found || target.version.AtLeast(required) {
+		return false, nil
+	}
+	if target.strict {
+		return false, &UnsupportedAttributeError{Key: key, Attribute: attr, Required: required, Detected: target.version}
+	}
+	return true, nil
+}
+
+// requiredVersion returns the minimum Pashua version for an attribute
+// and false if the attribute is supported by all versions
+func requiredVersion(typ string, attr string) (Version, bool) {
+	attributeVersionsMu.RLock()
+	defer attributeVersionsMu.RUnlock()
+	if v, found := attributeVersions[typ+"."+attr]; found {
+		return v, true
+	}
+	v, found := attributeVersions["*."+attr]
+	return v, found
+}
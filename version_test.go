@@ -0,0 +1,107 @@
+package pashua
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Version
+		err  bool
+	}{
+		{"0.11", Version{0, 11, 0}, false},
+		{" 0.9.4 ", Version{0, 9, 4}, false},
+		{"1", Version{1, 0, 0}, false},
+		{"1.2.3.4", Version{}, true},
+		{"0.x", Version{}, true},
+		{"-1.0", Version{}, true},
+	}
+	for _, test := range tests {
+		got, err := ParseVersion(test.s)
+		if (err != nil) != test.err || got != test.want {
+			t.Errorf("ParseVersion(%q) = %v, %v", test.s, got, err)
+		}
+	}
+	if !(Version{0, 11, 0}).AtLeast(Version{0, 9, 4}) || (Version{0, 9, 4}).AtLeast(Version{0, 10, 0}) {
+		t.Error("AtLeast compares versions wrongly")
+	}
+}
+
+func TestLocatorVersion(t *testing.T) {
+	plist := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+	<key>CFBundleName</key><string>Pashua</string>
+	<key>CFBundleShortVersionString</key><string>0.11</string>
+</dict></plist>`
+	files := fstest.MapFS{
+		"Applications/Pashua.app/Contents/Info.plist":   &fstest.MapFile{Data: []byte(plist)},
+		"Applications/Pashua.app/Contents/MacOS/Pashua": executable,
+	}
+	l := testLocator(files, nil)
+	v, err := l.Version("/Applications/Pashua.app/Contents/MacOS/Pashua")
+	if err != nil || v != (Version{0, 11, 0}) {
+		t.Errorf("Version = %v, %v, want 0.11", v, err)
+	}
+}
+
+func TestEncodeForVersion(t *testing.T) {
+	RegisterAttributeVersion("button", "relx", Version{0, 10, 0})
+	defer func() {
+		attributeVersionsMu.Lock()
+		delete(attributeVersions, "button.relx")
+		attributeVersionsMu.Unlock()
+	}()
+	win := &PashuaWindow{}
+	win.Elements.Add("b", PashuaButton{Label: "Help", RelX: 5})
+
+	config, err := win.EncodeForVersion(Version{0, 9, 4}, false)
+	if want := "b.type=button\nb.label=Help"; err != nil || config != want {
+		t.Errorf("old version: %q, %v, want %q", config, err, want)
+	}
+	config, err = win.EncodeForVersion(Version{0, 10, 0}, false)
+	if want := "b.type=button\nb.label=Help\nb.relx=5"; err != nil || config != want {
+		t.Errorf("new version: %q, %v, want %q", config, err, want)
+	}
+	_, err = win.EncodeForVersion(Version{0, 9, 4}, true)
+	var unsupported *UnsupportedAttributeError
+	if !errors.As(err, &unsupported) || unsupported.Attribute != "relx" {
+		t.Errorf("strict: error %v, want an *UnsupportedAttributeError for relx", err)
+	}
+}
+
+func TestLocatorVersionOnlyReadsPashuaPlist(t *testing.T) {
+	plist := func(name string, version string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(`<plist><dict><key>CFBundleName</key><string>` + name +
+			`</string><key>CFBundleShortVersionString</key><string>` + version + `</string></dict></plist>`)}
+	}
+	files := fstest.MapFS{
+		"Apps/Tool.app/Contents/Info.plist":      plist("Tool", "3.2"),
+		"Apps/Tool.app/Contents/MacOS/Pashua":    executable,
+		"Apps/Renamed.app/Contents/Info.plist":   plist("Pashua", "0.11"),
+		"Apps/Renamed.app/Contents/MacOS/Pashua": executable,
+		"Apps/Pashua.app/Contents/Info.plist":    plist("", "0.10"),
+		"Apps/Pashua.app/Contents/MacOS/Pashua":  executable,
+	}
+	l := testLocator(files, nil)
+	if v, err := l.Version("/Apps/Tool.app/Contents/MacOS/Pashua"); err == nil {
+		t.Errorf("host app plist: version %v, want an error", v)
+	}
+	if v, err := l.Version("/Apps/Renamed.app/Contents/MacOS/Pashua"); err != nil || v != (Version{0, 11, 0}) {
+		t.Errorf("plist naming Pashua: %v, %v", v, err)
+	}
+	if v, err := l.Version("/Apps/Pashua.app/Contents/MacOS/Pashua"); err != nil || v != (Version{0, 10, 0}) {
+		t.Errorf("Pashua.app: %v, %v", v, err)
+	}
+}
+
+func TestNoAttributeGatedByDefault(t *testing.T) {
+	win := &PashuaWindow{}
+	win.Elements.Add("c", PashuaCombobox{Rows: 5, CompletionMode: CaseInsensitive})
+	got, err := win.EncodeForVersion(Version{0, 1, 0}, true)
+	if want := "c.type=combobox\nc.rows=5\nc.completion=2"; err != nil || got != want {
+		t.Errorf("EncodeForVersion = %q, %v, want %q", got, err, want)
+	}
+}